@@ -2,17 +2,62 @@ package scli
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ArgsValidator is the function signature for representing an argument validator for Command's.
 type ArgsValidator func(args []string) error
 
+// ArgDef describes a positional arg of a Command in its ArgSpec.
+type ArgDef struct {
+	Name     string
+	Required bool
+	Variadic bool // accepts any number of args, only valid for the last ArgDef
+}
+
+// String formats the arg for a usage line, as <name> when it is required or [name] when it is optional, followed by
+// ... when it is variadic.
+func (a ArgDef) String() string {
+	name := a.Name
+	if a.Variadic {
+		name += "..."
+	}
+	if a.Required {
+		return "<" + name + ">"
+	}
+	return "[" + name + "]"
+}
+
+// argsValidator returns the ArgsValidator of c, or one derived from its ArgSpec if it has none.
+func (c *Command) argsValidator() ArgsValidator {
+	if c.ArgsValidator != nil || len(c.ArgSpec) == 0 {
+		return c.ArgsValidator
+	}
+
+	required := 0
+	for _, arg := range c.ArgSpec {
+		if arg.Required {
+			required++
+		}
+	}
+	if c.ArgSpec[len(c.ArgSpec)-1].Variadic {
+		return MinArgs(required)
+	}
+	return RangeArgs(required, len(c.ArgSpec))
+}
+
 // NoArgs returns an error if any args are included.
 func NoArgs() ArgsValidator {
 	return func(args []string) error {
 		if len(args) > 0 {
-			return fmt.Errorf("was expecting to receive no arguemetns received %d", len(args))
+			return fmt.Errorf("expected no arguments, received %d: %s", len(args), quoteArgs(args))
 		}
 		return nil
 	}
@@ -81,9 +126,168 @@ func OnlyValidArgs(validArgs []string) ArgsValidator {
 	}
 }
 
+// OnlyValidArgsFold is like OnlyValidArgs but matches args case-insensitively.
+// Errors list the valid args in the form they were passed to OnlyValidArgsFold.
+func OnlyValidArgsFold(validArgs []string) ArgsValidator {
+	if len(validArgs) == 0 {
+		return nil
+	}
+
+	validSet := make(map[string]struct{}, len(validArgs))
+	for _, arg := range validArgs {
+		validSet[strings.ToLower(arg)] = struct{}{}
+	}
+
+	return func(args []string) error {
+		for _, arg := range args {
+			if _, ok := validSet[strings.ToLower(arg)]; !ok {
+				return fmt.Errorf("requires valid arguments of %s, received %s", strings.Join(validArgs, ", "), arg)
+			}
+		}
+		return nil
+	}
+}
+
+// UniqueArgs returns an error if any arg is passed more than once.
+func UniqueArgs() ArgsValidator {
+	return func(args []string) error {
+		seen := make(map[string]struct{}, len(args))
+		for _, arg := range args {
+			if _, ok := seen[arg]; ok {
+				return fmt.Errorf("requires unique arg(s), received %q more than once", arg)
+			}
+			seen[arg] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// PositionalValidators returns an error unless there is exactly one arg for each validator, and each arg passes the
+// validator at the same position.
+func PositionalValidators(validators ...func(arg string) error) ArgsValidator {
+	return func(args []string) error {
+		if len(args) != len(validators) {
+			return fmt.Errorf("requires exactly %d arg(s), received %d", len(validators), len(args))
+		}
+
+		for i, v := range validators {
+			if err := v(args[i]); err != nil {
+				return fmt.Errorf("invalid arg %d (%q): %w", i+1, args[i], err)
+			}
+		}
+		return nil
+	}
+}
+
+// NoFlagLikeArgs returns an error if any arg begins with a "-", other than a lone "-" which commonly means stdin.
+// Such args are usually a misplaced flag, or a flag that was missing its value.
+func NoFlagLikeArgs() ArgsValidator {
+	return func(args []string) error {
+		for _, arg := range args {
+			if len(arg) > 1 && arg[0] == '-' {
+				return fmt.Errorf("received %q as an argument, did you mean to pass it as a flag before any arguments?", arg)
+			}
+		}
+		return nil
+	}
+}
+
+// OrderedArgs returns an error if any two adjacent args are out of order, that is if less(args[i+1], args[i]) is
+// true. Equal args are in order. For example OrderedArgs(func(a, b string) bool { return a < b }) requires sorted args.
+func OrderedArgs(less func(a, b string) bool) ArgsValidator {
+	return func(args []string) error {
+		for i := 1; i < len(args); i++ {
+			if less(args[i], args[i-1]) {
+				return fmt.Errorf("requires ordered arg(s), received %q before %q", args[i-1], args[i])
+			}
+		}
+		return nil
+	}
+}
+
+// GlobArgs returns an error if any arg does not match the shell glob pattern, using the syntax of path.Match, such as
+// "*.go". If pattern is malformed every call returns an error wrapping path.ErrBadPattern, whatever the args.
+func GlobArgs(pattern string) ArgsValidator {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return func(args []string) error {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return func(args []string) error {
+		for _, arg := range args {
+			if ok, _ := path.Match(pattern, arg); !ok {
+				return fmt.Errorf("requires arg(s) matching %q, received %q", pattern, arg)
+			}
+		}
+		return nil
+	}
+}
+
+// MaxTotalArgLen returns an error if the combined length in bytes of all args exceeds n, for commands that pass
+// their args on to another program and must stay within the argument length limits of the OS.
+func MaxTotalArgLen(n int) ArgsValidator {
+	return func(args []string) error {
+		total := 0
+		for _, arg := range args {
+			total += len(arg)
+		}
+		if total > n {
+			return fmt.Errorf("requires arg(s) no longer than %d bytes combined, received %d", n, total)
+		}
+		return nil
+	}
+}
+
+// AbsPathArgs returns an error for the first arg that is not an absolute path, as reported by filepath.IsAbs.
+func AbsPathArgs() ArgsValidator {
+	return func(args []string) error {
+		for _, arg := range args {
+			if !filepath.IsAbs(arg) {
+				return fmt.Errorf("requires absolute path arg(s), received %q", arg)
+			}
+		}
+		return nil
+	}
+}
+
+// RelPathArgs returns an error for the first arg that is not a relative path, as reported by filepath.IsAbs.
+func RelPathArgs() ArgsValidator {
+	return func(args []string) error {
+		for _, arg := range args {
+			if filepath.IsAbs(arg) {
+				return fmt.Errorf("requires relative path arg(s), received %q", arg)
+			}
+		}
+		return nil
+	}
+}
+
+// PrintableArgs returns an error if any arg is not valid UTF-8 or contains a character that is not printable, such
+// as a control character, naming the arg and the byte position of the character.
+func PrintableArgs() ArgsValidator {
+	return func(args []string) error {
+		for _, arg := range args {
+			for i, r := range arg {
+				if r == utf8.RuneError && strings.HasPrefix(arg[i:], "\uFFFD") {
+					continue // the replacement character itself is valid
+				}
+				if r == utf8.RuneError {
+					return fmt.Errorf("requires valid UTF-8 arg(s), received %q with an invalid byte at position %d", arg, i)
+				}
+				if !unicode.IsPrint(r) {
+					return fmt.Errorf("requires printable arg(s), received %q with %U at byte position %d", arg, r, i)
+				}
+			}
+		}
+		return nil
+	}
+}
+
 // CombineValidator is used for combining multiple ArgsValidator's into one.
 // It accepts multiple ArgsValidator functions and returns a single ArgsValidator,
 // that checks all conditions in order they are passed.
+// Use TraceValidators instead to see the result of every validator when debugging.
 func CombineValidator(validators ...ArgsValidator) ArgsValidator {
 	return func(args []string) error {
 		for _, v := range validators {
@@ -94,3 +298,66 @@ func CombineValidator(validators ...ArgsValidator) ArgsValidator {
 		return nil
 	}
 }
+
+// AnyValidator returns nil if at least one of validators accepts the args, checking them in the order they are passed.
+// If none do, a ValidatorChainError with the result of each validator is returned.
+func AnyValidator(validators ...ArgsValidator) ArgsValidator {
+	return func(args []string) error {
+		chainErr := ValidatorChainError{Results: make([]ValidatorResult, 0, len(validators))}
+		for i, v := range validators {
+			err := v(args)
+			if err == nil {
+				return nil
+			}
+			chainErr.Results = append(chainErr.Results, newValidatorResult(i, v, err))
+		}
+		if len(chainErr.Results) == 0 {
+			return nil
+		}
+		return chainErr
+	}
+}
+
+// TraceValidators is a debugging alternative to CombineValidator, that runs every validator rather than stopping at
+// the first failure. If any fail, a ValidatorChainError with the result of each validator is returned.
+func TraceValidators(validators ...ArgsValidator) ArgsValidator {
+	return func(args []string) error {
+		chainErr := ValidatorChainError{Results: make([]ValidatorResult, len(validators))}
+		failed := false
+		for i, v := range validators {
+			err := v(args)
+			failed = failed || err != nil
+			chainErr.Results[i] = newValidatorResult(i, v, err)
+		}
+		if !failed {
+			return nil
+		}
+		return chainErr
+	}
+}
+
+// newValidatorResult records the result of the validator v at index i, naming it after the function that created it,
+// such as MinArgs for the validator returned by MinArgs(1).
+func newValidatorResult(i int, v ArgsValidator, err error) ValidatorResult {
+	name := ""
+	if fn := runtime.FuncForPC(reflect.ValueOf(v).Pointer()); fn != nil {
+		name = fn.Name()
+		name = name[strings.LastIndex(name, "/")+1:]
+		if _, after, ok := strings.Cut(name, "."); ok {
+			name = after
+		}
+		if before, _, ok := strings.Cut(name, ".func"); ok {
+			name = before
+		}
+	}
+	return ValidatorResult{Index: i, Name: name, Err: err}
+}
+
+// quoteArgs formats args as a comma separated list of quoted strings for use in error messages.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = strconv.Quote(arg)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,260 @@
+package scli
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestOnlyValidArgsFold(t *testing.T) {
+	validator := OnlyValidArgsFold([]string{"json", "YAML"})
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "Exact Case", PassedArgs: []string{"json", "YAML"}},
+		{Name: "Mixed Case", PassedArgs: []string{"JSON", "yaml", "Json"}},
+		{Name: "No Args", PassedArgs: []string{}},
+		{Name: "Invalid Arg", PassedArgs: []string{"json", "toml"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := validator(tt.PassedArgs); (err != nil) != tt.WantErr {
+				t.Errorf("OnlyValidArgsFold() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestNoArgs(t *testing.T) {
+	if err := NoArgs()(nil); err != nil {
+		t.Errorf("NoArgs() error = %v, want nil", err)
+	}
+
+	want := `expected no arguments, received 2: "foo", "bar baz"`
+	if err := NoArgs()([]string{"foo", "bar baz"}); err == nil || err.Error() != want {
+		t.Errorf("NoArgs() error = %v, want %s", err, want)
+	}
+}
+
+func TestUniqueArgs(t *testing.T) {
+	validator := CombineValidator(ExactArgs(2), UniqueArgs())
+
+	if err := validator([]string{"a", "b"}); err != nil {
+		t.Errorf("UniqueArgs() error = %v, want nil", err)
+	}
+
+	if err := validator([]string{"a", "a"}); err == nil {
+		t.Error("UniqueArgs() error = nil, want error")
+	}
+}
+
+func TestPositionalValidators(t *testing.T) {
+	isInt := func(arg string) error {
+		_, err := strconv.Atoi(arg)
+		return err
+	}
+	validator := PositionalValidators(isInt, func(arg string) error {
+		return OnlyValidArgs([]string{"up", "down"})([]string{arg})
+	})
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "Valid", PassedArgs: []string{"42", "up"}},
+		{Name: "Too Few", PassedArgs: []string{"42"}, WantErr: true},
+		{Name: "Too Many", PassedArgs: []string{"42", "up", "down"}, WantErr: true},
+		{Name: "Invalid First", PassedArgs: []string{"foo", "up"}, WantErr: true},
+		{Name: "Invalid Second", PassedArgs: []string{"42", "left"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := validator(tt.PassedArgs); (err != nil) != tt.WantErr {
+				t.Errorf("PositionalValidators() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestNoFlagLikeArgs(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "Plain", PassedArgs: []string{"foo", "bar"}},
+		{Name: "Stdin", PassedArgs: []string{"-"}},
+		{Name: "Flag Like", PassedArgs: []string{"foo", "-bar"}, WantErr: true},
+		{Name: "Double Dash", PassedArgs: []string{"--"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := NoFlagLikeArgs()(tt.PassedArgs); (err != nil) != tt.WantErr {
+				t.Errorf("NoFlagLikeArgs() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestOrderedArgs(t *testing.T) {
+	validator := OrderedArgs(func(a, b string) bool { return a < b })
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "No Args", PassedArgs: []string{}},
+		{Name: "Sorted", PassedArgs: []string{"a", "b", "c"}},
+		{Name: "Equal", PassedArgs: []string{"a", "a"}},
+		{Name: "Unsorted", PassedArgs: []string{"a", "c", "b"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := validator(tt.PassedArgs); (err != nil) != tt.WantErr {
+				t.Errorf("OrderedArgs() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestGlobArgs(t *testing.T) {
+	validator := GlobArgs("*.go")
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "No Args", PassedArgs: []string{}},
+		{Name: "Matching", PassedArgs: []string{"main.go", "scli_test.go"}},
+		{Name: "Not Matching", PassedArgs: []string{"main.go", "README.md"}, WantErr: true},
+		{Name: "Separator", PassedArgs: []string{"cmd/main.go"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := validator(tt.PassedArgs); (err != nil) != tt.WantErr {
+				t.Errorf("GlobArgs() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+
+	if err := GlobArgs("[")([]string{}); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("GlobArgs() error = %v, want %v", err, path.ErrBadPattern)
+	}
+}
+
+func TestAnyValidator(t *testing.T) {
+	validator := AnyValidator(NoArgs(), ExactArgs(2))
+
+	if err := validator(nil); err != nil {
+		t.Errorf("AnyValidator() error = %v, want nil", err)
+	}
+	if err := validator([]string{"a", "b"}); err != nil {
+		t.Errorf("AnyValidator() error = %v, want nil", err)
+	}
+
+	err := validator([]string{"a"})
+	var chainErr ValidatorChainError
+	if !errors.As(err, &chainErr) {
+		t.Fatalf("AnyValidator() error = %v, want ValidatorChainError", err)
+	}
+	if len(chainErr.Results) != 2 || chainErr.Results[0].Name != "NoArgs" || chainErr.Results[1].Name != "ExactArgs" {
+		t.Errorf("Results = %+v, want failures of NoArgs and ExactArgs", chainErr.Results)
+	}
+}
+
+func TestTraceValidators(t *testing.T) {
+	errCustom := errors.New("custom")
+	validator := TraceValidators(MinArgs(1), UniqueArgs(), func(args []string) error {
+		return errCustom
+	})
+
+	err := validator([]string{"a", "a"})
+	want := `validators failed:
+  0 (MinArgs): ok
+  1 (UniqueArgs): requires unique arg(s), received "a" more than once
+  2 (TestTraceValidators): custom`
+	if err == nil || err.Error() != want {
+		t.Errorf("TraceValidators() error = %v, want %s", err, want)
+	}
+	if errors.Is(err, errCustom) {
+		t.Errorf("TraceValidators() error unwraps to %v, want the first failure", errCustom)
+	}
+
+	if err := TraceValidators(MinArgs(1))([]string{"a"}); err != nil {
+		t.Errorf("TraceValidators() error = %v, want nil", err)
+	}
+}
+
+func TestPrintableArgs(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    string
+	}{
+		{Name: "Printable", PassedArgs: []string{"hello world", "héllo", "�"}},
+		{Name: "Control Character", PassedArgs: []string{"ok", "a\x1b[0m"}, WantErr: `requires printable arg(s), received "a\x1b[0m" with U+001B at byte position 1`},
+		{Name: "Invalid UTF-8", PassedArgs: []string{"ab\xff"}, WantErr: `requires valid UTF-8 arg(s), received "ab\xff" with an invalid byte at position 2`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := PrintableArgs()(tt.PassedArgs)
+			if tt.WantErr == "" && err != nil || tt.WantErr != "" && (err == nil || err.Error() != tt.WantErr) {
+				t.Errorf("PrintableArgs() error = %v, want %s", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestPathArgs(t *testing.T) {
+	abs, err := filepath.Abs("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name       string
+		Validator  ArgsValidator
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "Absolute", Validator: AbsPathArgs(), PassedArgs: []string{abs}},
+		{Name: "Absolute Given Relative", Validator: AbsPathArgs(), PassedArgs: []string{abs, "file.txt"}, WantErr: true},
+		{Name: "Relative", Validator: RelPathArgs(), PassedArgs: []string{"file.txt", filepath.Join("..", "dir")}},
+		{Name: "Relative Given Absolute", Validator: RelPathArgs(), PassedArgs: []string{"file.txt", abs}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := tt.Validator(tt.PassedArgs); (err != nil) != tt.WantErr {
+				t.Errorf("validator error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestMaxTotalArgLen(t *testing.T) {
+	validator := MaxTotalArgLen(6)
+
+	if err := validator([]string{"abc", "def"}); err != nil {
+		t.Errorf("MaxTotalArgLen() error = %v, want nil", err)
+	}
+
+	want := "requires arg(s) no longer than 6 bytes combined, received 7"
+	if err := validator([]string{"abc", "defg"}); err == nil || err.Error() != want {
+		t.Errorf("MaxTotalArgLen() error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,82 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+)
+
+// CommandBuilder builds a Command with chained method calls, as an alternative to nested struct literals for large
+// trees. Problems found while building, such as subcommands that share a name, are returned by Build.
+type CommandBuilder struct {
+	cmd *Command
+	err error
+}
+
+// NewCommand starts building a Command with the given Usage.
+func NewCommand(usage string) *CommandBuilder {
+	return &CommandBuilder{cmd: &Command{Usage: usage}}
+}
+
+// Short sets the ShortHelp of the command.
+func (b *CommandBuilder) Short(help string) *CommandBuilder {
+	b.cmd.ShortHelp = help
+	return b
+}
+
+// Long sets the LongHelp of the command.
+func (b *CommandBuilder) Long(help string) *CommandBuilder {
+	b.cmd.LongHelp = help
+	return b
+}
+
+// Aliases adds to the Aliases of the command.
+func (b *CommandBuilder) Aliases(aliases ...string) *CommandBuilder {
+	b.cmd.Aliases = append(b.cmd.Aliases, aliases...)
+	return b
+}
+
+// Flags calls fn to define the flags of the command on its FlagSet, which is created named after the command if it
+// does not exist yet.
+func (b *CommandBuilder) Flags(fn func(fs *flag.FlagSet)) *CommandBuilder {
+	if b.cmd.FlagSet == nil {
+		b.cmd.FlagSet = flag.NewFlagSet(b.cmd.Name(), flag.ContinueOnError)
+	}
+	fn(b.cmd.FlagSet)
+	return b
+}
+
+// Args sets the ArgsValidator of the command.
+func (b *CommandBuilder) Args(validator ArgsValidator) *CommandBuilder {
+	b.cmd.ArgsValidator = validator
+	return b
+}
+
+// Run sets the Exec of the command.
+func (b *CommandBuilder) Run(exec ExecFunc) *CommandBuilder {
+	b.cmd.Exec = exec
+	return b
+}
+
+// Sub adds the commands built by subs to the Subcommands of the command. An ErrDuplicateCommand is recorded if any
+// of them shares a name or alias with another subcommand, along with any problem recorded by subs.
+func (b *CommandBuilder) Sub(subs ...*CommandBuilder) *CommandBuilder {
+	for _, sub := range subs {
+		if sub.err != nil && b.err == nil {
+			b.err = sub.err
+		}
+		b.cmd.Subcommands = append(b.cmd.Subcommands, sub.cmd)
+	}
+
+	if err := checkDuplicates(b.cmd.Subcommands, b.cmd.SubcommandNamePrefix); err != nil && b.err == nil {
+		b.err = fmt.Errorf("%s: %w", b.cmd.Name(), err)
+	}
+	return b
+}
+
+// Build returns the built Command, or the first problem found while building it.
+func (b *CommandBuilder) Build() (*Command, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.cmd, nil
+}
@@ -0,0 +1,47 @@
+package scli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestCommandBuilder(t *testing.T) {
+	var name *string
+	cmd, err := NewCommand("tool [flags]").
+		Short("a tool").
+		Flags(func(fs *flag.FlagSet) {
+			name = fs.String("name", "", "the name")
+		}).
+		Sub(
+			NewCommand("sync <dir>").Short("sync things").Aliases("s").Args(ExactArgs(1)).Run(returnsNil),
+			NewCommand("clean").Run(returnsNil),
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error %v", err)
+	}
+
+	if cmd.ShortHelp != "a tool" || cmd.FlagSet.Name() != "tool" || len(cmd.Subcommands) != 2 {
+		t.Fatalf("Build() = %+v", cmd)
+	}
+	if sync := cmd.Subcommands[0]; sync.Usage != "sync <dir>" || !reflect.DeepEqual(sync.Aliases, []string{"s"}) {
+		t.Errorf("Subcommands[0] = %+v", sync)
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"-name", "foo", "s", "dir"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+	if *name != "foo" {
+		t.Errorf("name = %s, want foo", *name)
+	}
+
+	_, err = NewCommand("tool").Sub(
+		NewCommand("a").Sub(NewCommand("sync"), NewCommand("other").Aliases("sync")),
+	).Build()
+	if !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("Build() error = %v, want %v", err, ErrDuplicateCommand)
+	}
+}
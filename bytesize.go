@@ -0,0 +1,106 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes that implements flag.Value, parsing human readable sizes such as 512, 10MB or 1.5GiB.
+// The suffixes KB, MB, GB and TB are powers of 1000, and KiB, MiB, GiB and TiB are powers of 1024. Suffixes are not
+// case sensitive, and a plain number or B suffix is a count of bytes.
+type ByteSize int64
+
+type byteUnit struct {
+	suffix string
+	size   int64
+}
+
+// byteUnits are the accepted suffixes in the order String prefers them, largest first with binary units before
+// decimal ones.
+var byteUnits = []byteUnit{
+	{"TiB", 1 << 40},
+	{"TB", 1e12},
+	{"GiB", 1 << 30},
+	{"GB", 1e9},
+	{"MiB", 1 << 20},
+	{"MB", 1e6},
+	{"KiB", 1 << 10},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// ByteSizeVar defines a ByteSize flag with the specified name, default value, and usage string.
+// The argument p points to a ByteSize variable in which to store the value of the flag.
+func ByteSizeVar(fs *flag.FlagSet, p *ByteSize, name string, value ByteSize, usage string) {
+	*p = value
+	fs.Var(p, name, usage)
+}
+
+// String formats b with the largest suffix that represents it exactly, such as 10MB or 1GiB.
+func (b *ByteSize) String() string {
+	if b == nil || *b == 0 {
+		return "0B"
+	}
+
+	for _, unit := range byteUnits {
+		if int64(*b)%unit.size == 0 {
+			return strconv.FormatInt(int64(*b)/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(*b), 10) + "B"
+}
+
+// Set parses s as a number of bytes with an optional suffix.
+func (b *ByteSize) Set(s string) error {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	num, suffix := s[:i], strings.TrimSpace(s[i:])
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || num == "" {
+		return fmt.Errorf("invalid size %q, must be a number with an optional suffix of %s", s, byteSuffixes())
+	}
+
+	size := int64(1)
+	if suffix != "" {
+		size = 0
+		for _, unit := range byteUnits {
+			if strings.EqualFold(suffix, unit.suffix) {
+				size = unit.size
+				break
+			}
+		}
+		if size == 0 {
+			return fmt.Errorf("invalid size suffix %q, must be one of %s", suffix, byteSuffixes())
+		}
+	}
+
+	bytes := n * float64(size)
+	if bytes >= math.MaxInt64 || bytes != math.Trunc(bytes) {
+		return fmt.Errorf("invalid size %q, must be a whole number of bytes no larger than %d", s, int64(math.MaxInt64))
+	}
+
+	*b = ByteSize(bytes)
+	return nil
+}
+
+// Type is the name of the value in the usage.
+func (b *ByteSize) Type() string {
+	return "size"
+}
+
+func byteSuffixes() string {
+	suffixes := make([]string, len(byteUnits))
+	for i, unit := range byteUnits {
+		suffixes[i] = unit.suffix
+	}
+	return strings.Join(suffixes, ", ")
+}
@@ -0,0 +1,76 @@
+package scli
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestByteSize_Set(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Value   string
+		Want    ByteSize
+		WantErr bool
+	}{
+		{Name: "Bytes", Value: "512", Want: 512},
+		{Name: "Bytes Suffix", Value: "512B", Want: 512},
+		{Name: "Decimal", Value: "10MB", Want: 10_000_000},
+		{Name: "Binary", Value: "10MiB", Want: 10 << 20},
+		{Name: "Fraction", Value: "1.5GiB", Want: 3 << 29},
+		{Name: "Case And Space", Value: "2 kib", Want: 2048},
+		{Name: "Unknown Suffix", Value: "10XB", WantErr: true},
+		{Name: "No Number", Value: "MB", WantErr: true},
+		{Name: "Partial Byte", Value: "0.5B", WantErr: true},
+		{Name: "Overflow", Value: "10000000TiB", WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b ByteSize
+			err := b.Set(tt.Value)
+			if (err != nil) != tt.WantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.WantErr)
+			}
+			if err == nil && b != tt.Want {
+				t.Errorf("Set() = %d, want %d", b, tt.Want)
+			}
+		})
+	}
+}
+
+func TestByteSize_String(t *testing.T) {
+	tests := []struct {
+		Size ByteSize
+		Want string
+	}{
+		{Size: 0, Want: "0B"},
+		{Size: 1500, Want: "1500B"},
+		{Size: 10_000_000, Want: "10MB"},
+		{Size: 1 << 30, Want: "1GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Want, func(t *testing.T) {
+			if got := tt.Size.String(); got != tt.Want {
+				t.Errorf("String() = %s, want %s", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestByteSizeVar(t *testing.T) {
+	var size ByteSize
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	ByteSizeVar(fs, &size, "max-size", 1<<20, "maximum size")
+
+	if def := fs.Lookup("max-size").DefValue; def != "1MiB" {
+		t.Errorf("DefValue = %s, want 1MiB", def)
+	}
+
+	if err := fs.Parse([]string{"-max-size", "10MB"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+	if size != 10_000_000 {
+		t.Errorf("size = %d, want 10000000", size)
+	}
+}
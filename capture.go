@@ -0,0 +1,68 @@
+package scli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RunWithTimeout parses and runs c with args under a context that expires after d, returning everything written to
+// the standard output and error of the command. Output is captured through the Stdout, Stderr and HelpOutput of c
+// and the output of every FlagSet in the tree that writes to os.Stderr, including those Parse would create, all of
+// which are restored afterwards, so subcommands with their own streams are not captured.
+//
+// Exec functions must honor the cancellation of their context for the deadline to stop them. If the deadline passed
+// before c returned, the error wraps ErrTimeout rather than any error of the command, so the two can be told apart.
+// Any previous parse of c is discarded first, and c is left unparsed afterwards, so it can be run repeatedly.
+func RunWithTimeout(c *Command, args []string, d time.Duration) (stdout, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+
+	origStdout, origStderr, origHelp := c.Stdout, c.Stderr, c.HelpOutput
+	c.Stdout, c.Stderr = &outBuf, &errBuf
+	if c.HelpOutput == nil {
+		c.HelpOutput = &outBuf
+	}
+	defer func() {
+		c.Stdout, c.Stderr, c.HelpOutput = origStdout, origStderr, origHelp
+	}()
+
+	// FlagSets that Parse would create are created here instead, so their usage is captured too
+	var redirected []*flag.FlagSet
+	var created []*Command
+	_ = c.Walk(func(path []*Command) error {
+		cmd := path[len(path)-1]
+		if cmd.FlagSet == nil {
+			cmd.FlagSet = flag.NewFlagSet(cmd.Name(), cmd.FlagErrorHandling)
+			created = append(created, cmd)
+		}
+		if fs := cmd.FlagSet; fs.Output() == os.Stderr {
+			fs.SetOutput(&errBuf)
+			redirected = append(redirected, fs)
+		}
+		return nil
+	})
+	defer func() {
+		for _, fs := range redirected {
+			fs.SetOutput(nil)
+		}
+		// the selection is cleared with the FlagSets, so a later Run does not use a parse without them
+		c.reset()
+		for _, cmd := range created {
+			cmd.FlagSet = nil
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	c.reset()
+	err = c.ParseAndRun(ctx, args)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %s", ErrTimeout, d)
+	}
+	return outBuf.String(), errBuf.String(), err
+}
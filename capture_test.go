@@ -0,0 +1,112 @@
+package scli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRunWithTimeout(t *testing.T) {
+	errCommand := errors.New("command failed")
+
+	tests := []struct {
+		Name       string
+		Args       []string
+		WantStdout string
+		WantStderr string
+		ErrCheck   func(err error) bool
+	}{
+		{Name: "Output", Args: []string{"echo", "hi"}, WantStdout: "hi\n", WantStderr: "echoed\n"},
+		{Name: "Command Error", Args: []string{"fail"}, ErrCheck: errorIs(errCommand)},
+		{Name: "Timeout", Args: []string{"sleep"}, WantStdout: "sleeping\n", ErrCheck: errorIs(ErrTimeout)},
+		{Name: "Help", Args: []string{"-h"}, WantStdout: "root\n", ErrCheck: errorIs(flag.ErrHelp)},
+		{
+			Name:       "Usage On Error",
+			Args:       []string{"strict", "x"},
+			WantStderr: "strict\n",
+			ErrCheck:   errorIs(ErrInvalidArguments),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cmd := &Command{
+				Usage:     "root",
+				UsageFunc: func(c *Command) string { return c.Name() },
+				Subcommands: []*Command{
+					{
+						Usage: "echo",
+						Exec: func(ctx context.Context, args []string) error {
+							fmt.Fprintln(StdoutFromContext(ctx), args[0])
+							fmt.Fprintln(StderrFromContext(ctx), "echoed")
+							return nil
+						},
+					},
+					{
+						Usage: "fail",
+						Exec: func(ctx context.Context, args []string) error {
+							return errCommand
+						},
+					},
+					{
+						Usage:         "strict",
+						UsageFunc:     func(c *Command) string { return c.Name() },
+						ArgsValidator: NoArgs(),
+						Exec: func(ctx context.Context, args []string) error {
+							return nil
+						},
+					},
+					{
+						Usage: "sleep",
+						Exec: func(ctx context.Context, args []string) error {
+							fmt.Fprintln(StdoutFromContext(ctx), "sleeping")
+							<-ctx.Done()
+							return ctx.Err()
+						},
+					},
+				},
+			}
+
+			stdout, stderr, err := RunWithTimeout(cmd, tt.Args, 10*time.Millisecond)
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Fatalf("RunWithTimeout() error = %v", err)
+			}
+			if stdout != tt.WantStdout || stderr != tt.WantStderr {
+				t.Errorf("RunWithTimeout() = %q, %q, want %q, %q", stdout, stderr, tt.WantStdout, tt.WantStderr)
+			}
+			if cmd.Stdout != nil || cmd.Stderr != nil || cmd.HelpOutput != nil || cmd.FlagSet != nil {
+				t.Error("RunWithTimeout() did not restore the streams of the command")
+			}
+		})
+	}
+}
+
+func TestRunWithTimeout_Repeated(t *testing.T) {
+	var got []string
+	cmd := &Command{
+		Usage: "root",
+		Subcommands: []*Command{{
+			Usage: "echo",
+			Exec: func(ctx context.Context, args []string) error {
+				got = args
+				return nil
+			},
+		}},
+	}
+
+	for _, arg := range []string{"a", "b"} {
+		if _, _, err := RunWithTimeout(cmd, []string{"echo", arg}, time.Second); err != nil {
+			t.Fatalf("RunWithTimeout() error %v", err)
+		}
+		if len(got) != 1 || got[0] != arg {
+			t.Errorf("args = %q, want [%s]", got, arg)
+		}
+	}
+
+	if err := cmd.Run(context.Background()); !errors.Is(err, ErrUnparsed) {
+		t.Errorf("Run() error = %v, want %v", err, ErrUnparsed)
+	}
+}
@@ -0,0 +1,276 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GenCompletion writes a shell completion script for c to w, completing the names and aliases of subcommands and the
+// names of flags from the command tree. Supported shells are "powershell" and "zsh". The zsh script groups
+// subcommands and flags, described by their ShortHelp and Usage.
+func (c *Command) GenCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "powershell":
+		return c.genPowerShellCompletion(w)
+	case "zsh":
+		return c.genZshCompletion(w)
+	default:
+		return fmt.Errorf("unsupported shell %q for completion", shell)
+	}
+}
+
+// CompleteTokens returns the completions the scripts of GenCompletion offer for the last word of line, a partial
+// command line that starts with the name of c, so completion can be tested without a shell. Words of line that name
+// a subcommand select it, and the subcommand names, aliases and flags of the selected command that start with the
+// last word are returned. A line ending in a space completes a new word. Nothing is completed after a "--"
+// separator or in place of the value of a flag. An error is returned if line cannot be split by Tokenize.
+func (c *Command) CompleteTokens(line string) ([]string, error) {
+	words, err := Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) > 0 {
+		words = words[1:] // the name of c
+	}
+
+	partial := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\t") {
+		partial, words = words[len(words)-1], words[:len(words)-1]
+	}
+
+	cmd := c
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "--":
+			return nil, nil
+		case strings.HasPrefix(word, "-"):
+			name := strings.TrimLeft(word, "-")
+			if strings.Contains(name, "=") || cmd.FlagSet == nil {
+				continue
+			}
+			if f := cmd.FlagSet.Lookup(name); f != nil && !isBoolFlag(f) {
+				if i == len(words)-1 {
+					return nil, nil // completing the value of the flag
+				}
+				i++
+			}
+		default:
+			if sub := cmd.subcommand(word); sub != nil {
+				cmd = sub
+			}
+		}
+	}
+
+	var completions []string
+	for _, word := range cmd.completionWords() {
+		if strings.HasPrefix(word, partial) {
+			completions = append(completions, word)
+		}
+	}
+	return completions, nil
+}
+
+// completionWords returns the subcommand names, aliases and flags that can follow c on the command line.
+func (c *Command) completionWords() []string {
+	var words []string
+	for _, sub := range c.enabledSubcommands() {
+		words = append(words, sub.typedNames(c.SubcommandNamePrefix)...)
+	}
+
+	for _, name := range c.flagNames() {
+		words = append(words, "-"+name)
+	}
+	return words
+}
+
+// flagNames returns the names of the flags listed in the usage of c, including the help flag.
+func (c *Command) flagNames() []string {
+	flags := c.HelpSections().Flags
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.Name
+	}
+	return names
+}
+
+//goland:noinspection GoUnhandledErrorResult
+func (c *Command) genPowerShellCompletion(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# powershell completion for %s\n", c.Name())
+	fmt.Fprintf(&b, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", psQuote(c.Name()))
+	fmt.Fprintln(&b, "    param($wordToComplete, $commandAst, $cursorPosition)")
+	fmt.Fprintln(&b)
+
+	var aliases []string
+	fmt.Fprintln(&b, "    $completions = @{")
+	_ = c.Walk(func(path []*Command) error {
+		cmd := path[len(path)-1]
+		key := commandPath(path)
+
+		words := cmd.completionWords()
+		for i, word := range words {
+			words[i] = psQuote(word)
+		}
+		fmt.Fprintf(&b, "        %s = @(%s)\n", psQuote(key), strings.Join(words, ", "))
+
+		for _, sub := range cmd.enabledSubcommands() {
+			names := sub.typedNames(cmd.SubcommandNamePrefix)
+			for _, alias := range names[1:] {
+				aliases = append(aliases, fmt.Sprintf("        %s = %s\n", psQuote(key+" "+alias), psQuote(key+" "+names[0])))
+			}
+		}
+		return nil
+	})
+	fmt.Fprintln(&b, "    }")
+	fmt.Fprintln(&b)
+
+	fmt.Fprintln(&b, "    $aliases = @{")
+	b.WriteString(strings.Join(aliases, ""))
+	fmt.Fprintln(&b, "    }")
+	fmt.Fprintln(&b)
+
+	fmt.Fprintf(&b, "    $path = %s\n", psQuote(c.Name()))
+	fmt.Fprint(&b, `    foreach ($element in $commandAst.CommandElements | Select-Object -Skip 1) {
+        $word = $element.ToString()
+        if ($element.Extent.EndOffset -ge $cursorPosition) { break }
+        $next = "$path $word"
+        if ($aliases.ContainsKey($next)) { $next = $aliases[$next] }
+        if ($completions.ContainsKey($next)) { $path = $next }
+    }
+
+    $completions[$path] | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+//goland:noinspection GoUnhandledErrorResult
+func (c *Command) genZshCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := "_" + zshIdent(c.Name())
+
+	fmt.Fprintf(&b, "#compdef %s\n\n", c.Name())
+	fmt.Fprintf(&b, "# zsh completion for %s\n", c.Name())
+	fmt.Fprintf(&b, "%s() {\n", fn)
+
+	var paths, aliases, cases []string
+	_ = c.Walk(func(path []*Command) error {
+		cmd := path[len(path)-1]
+		key := commandPath(path)
+		paths = append(paths, zshQuote(key))
+
+		var body strings.Builder
+		fmt.Fprintf(&body, "        %s)\n", zshQuote(key))
+		body.WriteString("            commands=(")
+		for _, sub := range cmd.enabledSubcommands() {
+			names := sub.typedNames(cmd.SubcommandNamePrefix)
+			for i, name := range names {
+				fmt.Fprintf(&body, "\n                %s", zshQuote(strings.ReplaceAll(name, ":", `\:`)+":"+sub.ShortHelp))
+				if i > 0 {
+					aliases = append(aliases, fmt.Sprintf("        %s %s\n", zshQuote(key+" "+name), zshQuote(key+" "+names[0])))
+				}
+			}
+		}
+		body.WriteString("\n            )\n")
+
+		body.WriteString("            options=(")
+		for _, spec := range cmd.zshOptionSpecs() {
+			fmt.Fprintf(&body, "\n                %s", zshQuote(spec))
+		}
+		body.WriteString("\n            )\n")
+		body.WriteString("            ;;\n")
+
+		cases = append(cases, body.String())
+		return nil
+	})
+
+	fmt.Fprintf(&b, "    local cmd=%s next i skip=1\n", zshQuote(c.Name()))
+	fmt.Fprintf(&b, "    local -a known=(%s)\n", strings.Join(paths, " "))
+	fmt.Fprintln(&b, "    local -A aliases=(")
+	b.WriteString(strings.Join(aliases, ""))
+	fmt.Fprintln(&b, "    )")
+	fmt.Fprint(&b, `
+    for (( i = 2; i < CURRENT; i++ )); do
+        next="$cmd ${words[i]}"
+        [[ -n ${aliases[$next]} ]] && next=${aliases[$next]}
+        if (( ${known[(Ie)$next]} )); then
+            cmd=$next
+            skip=$i
+        fi
+    done
+
+    # complete as if the selected subcommand was the command
+    words=("${(@)words[skip,-1]}")
+    (( CURRENT -= skip - 1 ))
+
+    local -a commands options
+    case $cmd in
+`)
+	b.WriteString(strings.Join(cases, ""))
+	fmt.Fprint(&b, `    esac
+
+    local ret=1
+    _arguments : "${options[@]}" && ret=0
+    if (( ${#commands} )); then
+        _describe -t commands 'commands' commands && ret=0
+    fi
+    return ret
+}
+
+`)
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, c.Name())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zshOptionSpecs returns the _arguments specs of the flags of c, with the usage of each flag as its description.
+func (c *Command) zshOptionSpecs() []string {
+	escape := strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace
+
+	var specs []string
+	for _, name := range c.flagNames() {
+		var f *flag.Flag
+		if c.FlagSet != nil {
+			f = c.FlagSet.Lookup(name)
+		}
+
+		switch {
+		case f == nil:
+			specs = append(specs, fmt.Sprintf("-%s[%s]", name, escape(c.messages().HelpFlag)))
+		case isBoolFlag(f):
+			specs = append(specs, fmt.Sprintf("-%s[%s]", name, escape(flagUsage(f))))
+		default:
+			specs = append(specs, fmt.Sprintf("-%s=[%s]:%s:", name, escape(flagUsage(f)), escape(flagType(f))))
+		}
+	}
+	return specs
+}
+
+// zshQuote quotes s as a zsh single quoted string.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshIdent replaces the characters of s that are not valid in a zsh function name.
+func zshIdent(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// psQuote quotes s as a PowerShell single quoted string.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
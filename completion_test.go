@@ -0,0 +1,137 @@
+package scli
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestCommand_GenCompletion(t *testing.T) {
+	subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = subFlags.String("name", "", "name")
+	_ = subFlags.Bool("force", false, "force [y]")
+
+	cmd := &Command{
+		Usage:       "root",
+		Subcommands: []*Command{{Usage: "sub", Aliases: []string{"s"}, ShortHelp: "it's a sub", FlagSet: subFlags}},
+	}
+
+	prefixed := &Command{
+		Usage:                "root",
+		SubcommandNamePrefix: "root-",
+		Subcommands:          []*Command{{Usage: "root-sub", Aliases: []string{"root-s"}}},
+	}
+
+	tests := []struct {
+		Name    string
+		Command *Command
+		Shell   string
+		Want    []string
+	}{
+		{
+			Name:    "PowerShell",
+			Command: cmd,
+			Shell:   "powershell",
+			Want: []string{
+				"Register-ArgumentCompleter -Native -CommandName 'root'",
+				"'root' = @('sub', 's', '-h')",
+				"'root sub' = @('-force', '-name', '-h')",
+				"'root s' = 'root sub'",
+			},
+		},
+		{
+			Name:    "PowerShell Prefixed",
+			Command: prefixed,
+			Shell:   "powershell",
+			Want: []string{
+				"'root' = @('sub', 's', '-h')",
+				"'root sub' = @('-h')",
+				"'root s' = 'root sub'",
+			},
+		},
+		{
+			Name:    "Zsh",
+			Command: cmd,
+			Shell:   "zsh",
+			Want: []string{
+				"#compdef root",
+				"local -a known=('root' 'root sub')",
+				"'root s' 'root sub'",
+				`'sub:it'\''s a sub'`,
+				`'-force[force \[y\]]'`,
+				`'-name=[name]:string:'`,
+				"'-h[prints help and usage for this command or subcommand]'",
+				"_describe -t commands 'commands' commands",
+				"compdef _root root",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.Command.GenCompletion(&b, tt.Shell); err != nil {
+				t.Fatalf("GenCompletion() error %v", err)
+			}
+
+			for _, want := range tt.Want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("GenCompletion() missing %q in:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+
+	if err := cmd.GenCompletion(&strings.Builder{}, "tcsh"); err == nil {
+		t.Error("GenCompletion() error = nil for unsupported shell")
+	}
+}
+
+func TestCommand_CompleteTokens(t *testing.T) {
+	rootFlags := flag.NewFlagSet("tool", flag.ContinueOnError)
+	_ = rootFlags.String("config", "", "config file")
+	_ = rootFlags.Bool("verbose", false, "verbose")
+
+	cmd := Command{
+		Usage:   "tool",
+		FlagSet: rootFlags,
+		Subcommands: []*Command{{
+			Usage: "git",
+			Subcommands: []*Command{
+				{Usage: "commit", Aliases: []string{"ci"}},
+				{Usage: "push"},
+			},
+		}},
+	}
+
+	tests := []struct {
+		Name string
+		Line string
+		Want []string
+	}{
+		{Name: "Root", Line: "tool ", Want: []string{"git", "-config", "-verbose", "-h"}},
+		{Name: "Subcommands", Line: "tool git ", Want: []string{"commit", "ci", "push", "-h"}},
+		{Name: "Partial", Line: "tool git c", Want: []string{"commit", "ci"}},
+		{Name: "Partial Flag", Line: "tool -v", Want: []string{"-verbose"}},
+		{Name: "After Flags", Line: "tool -verbose -config c.json git p", Want: []string{"push"}},
+		{Name: "Flag Value", Line: "tool -config ", Want: nil},
+		{Name: "After Separator", Line: "tool -- ", Want: nil},
+		{Name: "No Match", Line: "tool git x", Want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := cmd.CompleteTokens(tt.Line)
+			if err != nil {
+				t.Fatalf("CompleteTokens() error %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.Want, ",") {
+				t.Errorf("CompleteTokens(%q) = %q, want %q", tt.Line, got, tt.Want)
+			}
+		})
+	}
+
+	if _, err := cmd.CompleteTokens(`tool "git`); err == nil {
+		t.Error("CompleteTokens() error = nil for an unterminated quote")
+	}
+}
@@ -0,0 +1,228 @@
+package scli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig sets the flags of the commands selected by Parse from the config file at path, skipping flags that were
+// set on the command line or from the environment, then checks the FlagValidators of those commands. It must be
+// called after Parse and before Run. As Parse has already checked AtLeastOneFlag, use ConfigFile instead for values
+// that should satisfy it.
+//
+// The format is detected from the extension of path, .json for JSON and .yaml or .yml for YAML, falling back to the
+// contents of the file for other extensions. Keys are flag names, prefixed with the path of subcommand names below c
+// to set the flags of subcommands, so "verbose" sets the flag verbose of c and "db.migrate.steps" sets the flag steps
+// of the subcommand migrate of db. JSON and YAML may nest subcommands as objects instead of joining them with dots.
+// The flat format has a key=value pair on each line, with blank lines and lines starting with # ignored.
+// A key given a list or repeated in the flat format sets its flag once for each value.
+//
+// Keys that do not refer to a flag of any command in the tree are an error unless LaxConfig is set. Keys of
+// subcommands that were not selected are checked but not applied, so one file can configure the whole tree.
+func (c *Command) LoadConfig(path string) error {
+	if c.selected == nil {
+		return ErrUnparsed
+	}
+
+	values, err := c.readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range c.selectedChain() {
+		if err := cmd.applyConfig(c, path, values); err != nil {
+			return err
+		}
+		if cmd.FlagSet != nil {
+			if err := cmd.validateFlags(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readConfig reads the config file at path, returning the values of the keys that refer to a flag of c or one of its
+// descendants. Other keys are an error unless LaxConfig is set.
+func (c *Command) readConfig(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parseConfig(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	for _, key := range sortedKeys(values) {
+		if cmd, _ := c.configFlag(key); cmd == nil {
+			if !c.LaxConfig {
+				return nil, fmt.Errorf("config %s: unknown key %q", path, key)
+			}
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
+
+// applyConfig sets the flags of c from the values of the config file at path, whose keys are relative to root.
+// Flags that are already set are skipped.
+func (c *Command) applyConfig(root *Command, path string, values map[string][]string) error {
+	if c.FlagSet == nil {
+		return nil
+	}
+
+	set := make(map[string]bool)
+	c.FlagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	for _, key := range sortedKeys(values) {
+		if cmd, name := root.configFlag(key); cmd == c && !set[name] {
+			for _, value := range values[key] {
+				if err := c.FlagSet.Set(name, value); err != nil {
+					return fmt.Errorf("config %s: invalid value %q for key %s: %w", path, value, key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of values in order.
+func sortedKeys(values map[string][]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configFlag resolves a dotted config key to the command below c and the name of its flag that the key refers to.
+// Returns a nil command if there is no such flag.
+func (c *Command) configFlag(key string) (*Command, string) {
+	cmd := c
+	parts := strings.Split(key, ".")
+	for len(parts) > 1 {
+		sub := cmd.subcommand(parts[0])
+		if sub == nil {
+			break
+		}
+		cmd, parts = sub, parts[1:]
+	}
+
+	name := strings.Join(parts, ".")
+	if cmd.FlagSet == nil || cmd.FlagSet.Lookup(name) == nil {
+		return nil, ""
+	}
+	return cmd, name
+}
+
+// parseConfig parses data in the format detected from path or the data itself into the values of each dotted key.
+func parseConfig(path string, data []byte) (map[string][]string, error) {
+	var tree map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".json" || ext != ".yaml" && ext != ".yml" && bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")):
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		if err := d.Decode(&tree); err != nil {
+			return nil, err
+		}
+	case ext == ".yaml" || ext == ".yml" || isYAMLConfig(data):
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+	default:
+		return parseFlatConfig(data)
+	}
+
+	values := make(map[string][]string)
+	if err := flattenConfig("", tree, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// isYAMLConfig reports whether the first line of data with content looks like a YAML mapping rather than a flat
+// key=value pair.
+func isYAMLConfig(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "---" {
+			return true
+		}
+		colon, eq := strings.Index(line, ":"), strings.Index(line, "=")
+		return colon >= 0 && (eq < 0 || colon < eq)
+	}
+	return false
+}
+
+// parseFlatConfig parses lines of key=value pairs, where values may be quoted as Go strings.
+func parseFlatConfig(data []byte) (map[string][]string, error) {
+	values := make(map[string][]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected key=value, received %q", i+1, line)
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value %s", i+1, value)
+			}
+			value = unquoted
+		}
+		values[key] = append(values[key], value)
+	}
+	return values, nil
+}
+
+// flattenConfig adds the scalar values of v to values, joining the keys of nested objects to prefix with dots.
+func flattenConfig(prefix string, v any, values map[string][]string) error {
+	switch v := v.(type) {
+	case nil:
+	case map[string]any:
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if err := flattenConfig(key, child, values); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range v {
+			switch child.(type) {
+			case map[string]any, []any:
+				return fmt.Errorf("invalid value for key %s, lists may only contain values", prefix)
+			}
+			values[prefix] = append(values[prefix], fmt.Sprint(child))
+		}
+	default:
+		if prefix == "" {
+			return fmt.Errorf("expected an object, received %v", v)
+		}
+		values[prefix] = append(values[prefix], fmt.Sprint(v))
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package scli
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommand_LoadConfig(t *testing.T) {
+	tests := []struct {
+		Name      string
+		File      string
+		Data      string
+		Args      []string
+		Lax       bool
+		WantName  string
+		WantSteps int
+		WantTags  []string
+		WantErr   string
+	}{
+		{
+			Name:      "Flat",
+			File:      "config",
+			Data:      "# comment\nname = \"from config\"\nsub.steps=3\nsub.tag=a\nsub.tag=b\n",
+			Args:      []string{"sub"},
+			WantName:  "from config",
+			WantSteps: 3,
+			WantTags:  []string{"a", "b"},
+		},
+		{
+			Name:      "JSON",
+			File:      "config.json",
+			Data:      `{"name": "from config", "sub": {"steps": 1000000, "tag": ["a", "b"]}}`,
+			Args:      []string{"sub"},
+			WantName:  "from config",
+			WantSteps: 1000000,
+			WantTags:  []string{"a", "b"},
+		},
+		{
+			Name:      "YAML",
+			File:      "config.yaml",
+			Data:      "name: from config\nsub:\n  steps: 3\n  tag: [a, b]\n",
+			Args:      []string{"sub"},
+			WantName:  "from config",
+			WantSteps: 3,
+			WantTags:  []string{"a", "b"},
+		},
+		{
+			Name:      "Detected YAML",
+			File:      "config",
+			Data:      "sub.steps: 3\n",
+			Args:      []string{"sub"},
+			WantSteps: 3,
+		},
+		{
+			Name:      "Command Line Wins",
+			File:      "config.json",
+			Data:      `{"name": "from config", "sub.steps": 3}`,
+			Args:      []string{"-name", "from args", "sub", "-steps", "4"},
+			WantName:  "from args",
+			WantSteps: 4,
+		},
+		{
+			Name:     "Unselected Subcommand",
+			File:     "config.json",
+			Data:     `{"name": "from config", "sub": {"steps": 3}}`,
+			Args:     []string{},
+			WantName: "from config",
+		},
+		{
+			Name:    "Unknown Key",
+			File:    "config.json",
+			Data:    `{"sub": {"nope": 3}}`,
+			Args:    []string{"sub"},
+			WantErr: `unknown key "sub.nope"`,
+		},
+		{
+			Name:      "Lax Unknown Key",
+			File:      "config.json",
+			Data:      `{"nope": 3, "sub": {"steps": 3}}`,
+			Args:      []string{"sub"},
+			Lax:       true,
+			WantSteps: 3,
+		},
+		{
+			Name:    "Invalid Value",
+			File:    "config",
+			Data:    "sub.steps=three\n",
+			Args:    []string{"sub"},
+			WantErr: `invalid value "three" for key sub.steps`,
+		},
+		{
+			Name:    "Flag Validator",
+			File:    "config",
+			Data:    "name=bad\n",
+			Args:    []string{},
+			WantErr: `invalid value "bad" for flag -name`,
+		},
+		{
+			Name:    "Malformed Flat",
+			File:    "config",
+			Data:    "name\n",
+			Args:    []string{},
+			WantErr: "line 1: expected key=value",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []string{"ConfigFile", "LoadConfig"} {
+			t.Run(tt.Name+"/"+mode, func(t *testing.T) {
+				rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+				rootFlags.SetOutput(io.Discard)
+				name := rootFlags.String("name", "", "the name")
+				subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+				steps := subFlags.Int("steps", 0, "the steps")
+				var tags stringsValue
+				subFlags.Var(&tags, "tag", "a tag")
+
+				path := filepath.Join(t.TempDir(), tt.File)
+				if err := os.WriteFile(path, []byte(tt.Data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				cmd := &Command{
+					Usage:          "root",
+					FlagSet:        rootFlags,
+					FlagValidators: map[string]func(string) error{"name": notBad},
+					LaxConfig:      tt.Lax,
+					Subcommands:    []*Command{{Usage: "sub", FlagSet: subFlags, Exec: returnsNil}},
+					Exec:           returnsNil,
+				}
+
+				var err error
+				if mode == "ConfigFile" {
+					cmd.ConfigFile = path
+					err = cmd.Parse(tt.Args)
+				} else {
+					if err := cmd.Parse(tt.Args); err != nil {
+						t.Fatalf("Parse() error %v", err)
+					}
+					err = cmd.LoadConfig(path)
+				}
+
+				if tt.WantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.WantErr) {
+						t.Fatalf("%s error = %v, want %s", mode, err, tt.WantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("%s error %v", mode, err)
+				}
+
+				if *name != tt.WantName || *steps != tt.WantSteps || !reflect.DeepEqual([]string(tags), tt.WantTags) {
+					t.Errorf("flags = %q, %d, %q, want %q, %d, %q", *name, *steps, tags, tt.WantName, tt.WantSteps, tt.WantTags)
+				}
+			})
+		}
+	}
+}
+
+func TestCommand_ConfigFileAtLeastOneFlag(t *testing.T) {
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	token := fs.String("token", "", "the token")
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("token=secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &Command{
+		Usage:          "root",
+		FlagSet:        fs,
+		ConfigFile:     path,
+		AtLeastOneFlag: [][]string{{"token"}},
+		Exec:           returnsNil,
+	}
+	if err := cmd.Parse(nil); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+	if *token != "secret" {
+		t.Errorf("token = %q, want %q", *token, "secret")
+	}
+}
+
+func notBad(value string) error {
+	if value == "bad" {
+		return errors.New("must not be bad")
+	}
+	return nil
+}
+
+func TestCommand_LoadConfigUnparsed(t *testing.T) {
+	cmd := &Command{Usage: "root", Exec: returnsNil}
+	if err := cmd.LoadConfig("config.json"); err != ErrUnparsed {
+		t.Errorf("LoadConfig() error = %v, want %v", err, ErrUnparsed)
+	}
+}
+
+type stringsValue []string
+
+func (s *stringsValue) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsValue) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
@@ -0,0 +1,151 @@
+package scli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type contextKey int
+
+const (
+	outputKey contextKey = iota
+	stdinKey
+	stdoutKey
+	stderrKey
+	warningsKey
+)
+
+// warnings collects the messages passed to AddWarning during a Run.
+type warnings struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+// OutputFromContext returns the writer that an Exec function should write its output and progress messages to.
+// Run sets it from the Output of the selected command or its nearest parent that defines one, or io.Discard if any
+// of them are Quiet. Defaults to os.Stdout if ctx was not passed through Run.
+func OutputFromContext(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey).(io.Writer); ok {
+		return w
+	}
+	return os.Stdout
+}
+
+// StdinFromContext returns the reader an Exec function should read input from. Run sets it from the Stdin of the
+// selected command or its nearest parent that defines one. Defaults to os.Stdin.
+func StdinFromContext(ctx context.Context) io.Reader {
+	if r, ok := ctx.Value(stdinKey).(io.Reader); ok {
+		return r
+	}
+	return os.Stdin
+}
+
+// StdoutFromContext returns the writer for the standard output of an Exec function, such as to pass to a child
+// process. Run sets it from the Stdout of the selected command or its nearest parent that defines one. Defaults to
+// os.Stdout. Unlike OutputFromContext it is not affected by Output, Quiet or BufferOutput.
+func StdoutFromContext(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(stdoutKey).(io.Writer); ok {
+		return w
+	}
+	return os.Stdout
+}
+
+// StderrFromContext returns the writer an Exec function should write errors and diagnostics to. Run sets it from the
+// Stderr of the selected command or its nearest parent that defines one. Defaults to os.Stderr.
+func StderrFromContext(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(stderrKey).(io.Writer); ok {
+		return w
+	}
+	return os.Stderr
+}
+
+// AddWarning records a non-fatal warning, such as a skipped item, that Run prints to the Stderr of the command once
+// Exec returns, whether or not it succeeded, so warnings are shown together rather than mixed into the output.
+// Outside of Run the warning is written to os.Stderr straight away. Safe for concurrent use.
+func AddWarning(ctx context.Context, msg string) {
+	w, ok := ctx.Value(warningsKey).(*warnings)
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.msgs = append(w.msgs, msg)
+}
+
+// Warnings returns the warnings recorded with AddWarning so far during the Run that ctx was passed through.
+func Warnings(ctx context.Context) []string {
+	w, ok := ctx.Value(warningsKey).(*warnings)
+	if !ok {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.msgs...)
+}
+
+// withWarnings returns ctx with a new warning collector and a func that prints the collected warnings to w, unless
+// ctx already has a collector from an enclosing Run, which prints them instead.
+func withWarnings(ctx context.Context, w io.Writer) (context.Context, func()) {
+	if _, ok := ctx.Value(warningsKey).(*warnings); ok {
+		return ctx, func() {}
+	}
+
+	collector := &warnings{}
+	return context.WithValue(ctx, warningsKey, collector), func() {
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		for _, msg := range collector.msgs {
+			_, _ = fmt.Fprintf(w, "Warning: %s\n", msg)
+		}
+	}
+}
+
+// withStreams returns ctx with the standard streams of the last command of chain.
+func withStreams(ctx context.Context, chain []*Command) context.Context {
+	var (
+		stdin          io.Reader = os.Stdin
+		stdout, stderr io.Writer = os.Stdout, os.Stderr
+	)
+	for _, cmd := range chain {
+		if cmd.Stdin != nil {
+			stdin = cmd.Stdin
+		}
+		if cmd.Stdout != nil {
+			stdout = cmd.Stdout
+		}
+		if cmd.Stderr != nil {
+			stderr = cmd.Stderr
+		}
+	}
+
+	ctx = context.WithValue(ctx, stdinKey, stdin)
+	ctx = context.WithValue(ctx, stdoutKey, stdout)
+	return context.WithValue(ctx, stderrKey, stderr)
+}
+
+// chainOutput resolves the output writer for the last command of chain.
+func chainOutput(chain []*Command) io.Writer {
+	for _, cmd := range chain {
+		if cmd.Quiet {
+			return io.Discard
+		}
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Output != nil {
+			return chain[i].Output
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Stdout != nil {
+			return chain[i].Stdout
+		}
+	}
+	return os.Stdout
+}
@@ -0,0 +1,51 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix joins the EnvPrefix of each command in chain with underscores, skipping empty prefixes.
+func envPrefix(chain []*Command) string {
+	var parts []string
+	for _, cmd := range chain {
+		if p := strings.Trim(cmd.EnvPrefix, "_"); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// envName returns the environment variable for the flag name under prefix, for example APP_DB_MAX_CONNS for the
+// flag max-conns under the prefix APP_DB.
+func envName(prefix, name string) string {
+	return prefix + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// setFromEnv sets every flag of c that was not set on the command line from its environment variable, if present.
+func (c *Command) setFromEnv(prefix string) error {
+	set := make(map[string]bool)
+	c.FlagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var err error
+	c.FlagSet.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] {
+			return
+		}
+
+		name := envName(prefix, f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if setErr := c.FlagSet.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for environment variable %s: %w", value, name, setErr)
+		}
+	})
+	return err
+}
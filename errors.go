@@ -1,13 +1,27 @@
 package scli
 
 import (
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"strings"
 )
 
 var (
 	ErrUnparsed         = errors.New("command tree is unparsed, can't run")
 	ErrInvalidArguments = errors.New("invalid arguments")
+	ErrDuplicateCommand = errors.New("duplicate command name")
+	ErrMaxDepth         = errors.New("command tree exceeds max depth")
+	ErrCommandCycle     = errors.New("command is its own ancestor")
+	ErrUnknownCommand   = errors.New("unknown command")
+	ErrAmbiguousCommand = errors.New("ambiguous command")
+	ErrSharedFlagSet    = errors.New("flag set is shared between commands")
+	ErrNotTerminal      = errors.New("this command requires an interactive terminal")
+	ErrAlreadyRunning   = errors.New("already running")
+	ErrTimeout          = errors.New("command timed out")
+	ErrShowUsage        = errors.New("show usage")
 )
 
 type NoExecError struct {
@@ -17,3 +31,176 @@ type NoExecError struct {
 func (e NoExecError) Error() string {
 	return fmt.Sprintf("terminal command (%s) does not define a Exec function", e.Command.Name())
 }
+
+// flagParseError is an error of FlagSet.Parse, which the FlagSet has already written to its output with the usage.
+type flagParseError struct {
+	err error
+}
+
+func (e flagParseError) Error() string {
+	return e.err.Error()
+}
+
+func (e flagParseError) Unwrap() error {
+	return e.err
+}
+
+// InvalidArgumentsError is returned by Parse when the args or flags of a command are rejected, such as by its
+// ArgsValidator. It matches ErrInvalidArguments and unwraps to the original error, so the error of a validator can
+// still be retrieved with errors.As.
+type InvalidArgumentsError struct {
+	Err error
+	msg string // the error formatted with the InvalidArguments message of the command
+}
+
+func (e InvalidArgumentsError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	return fmt.Sprintf("%v: %v", ErrInvalidArguments, e.Err)
+}
+
+func (e InvalidArgumentsError) Is(target error) bool {
+	return target == ErrInvalidArguments
+}
+
+func (e InvalidArgumentsError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownSubcommandError is returned by Parse when UnknownSubcommandIsError is set and the first arg of a command
+// does not select a subcommand. It matches ErrUnknownCommand.
+type UnknownSubcommandError struct {
+	Command     *Command
+	Name        string   // the arg that did not select a subcommand
+	Suggestions []string // names of subcommands similar to Name, most similar first
+}
+
+func (e UnknownSubcommandError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "unknown subcommand %q for %q", e.Name, e.Command.Name())
+	if len(e.Suggestions) > 0 {
+		fmt.Fprintf(&b, ", did you mean %s?", quoteArgs(e.Suggestions))
+	}
+
+	var names []string
+	for _, sub := range e.Command.Subcommands {
+		if e.Command.subcommandEnabled(sub) {
+			names = append(names, strings.TrimPrefix(sub.Name(), e.Command.SubcommandNamePrefix))
+		}
+	}
+	fmt.Fprintf(&b, " (available: %s)", strings.Join(names, ", "))
+	return b.String()
+}
+
+func (e UnknownSubcommandError) Is(target error) bool {
+	return target == ErrUnknownCommand
+}
+
+// ValidatorChainError is returned by AnyValidator and TraceValidators, reporting the result of each validator they
+// ran. It unwraps to the first error of the validators.
+type ValidatorChainError struct {
+	Results []ValidatorResult
+}
+
+// ValidatorResult is the result of a single validator of a ValidatorChainError.
+type ValidatorResult struct {
+	Index int    // the position of the validator in the arguments
+	Name  string // the name of the function that created the validator, such as MinArgs, may be empty
+	Err   error  // nil if the validator accepted the args
+}
+
+func (e ValidatorChainError) Error() string {
+	var b strings.Builder
+	b.WriteString("validators failed:")
+	for _, r := range e.Results {
+		fmt.Fprintf(&b, "\n  %d", r.Index)
+		if r.Name != "" {
+			fmt.Fprintf(&b, " (%s)", r.Name)
+		}
+		if r.Err != nil {
+			fmt.Fprintf(&b, ": %v", r.Err)
+		} else {
+			b.WriteString(": ok")
+		}
+	}
+	return b.String()
+}
+
+func (e ValidatorChainError) Unwrap() error {
+	for _, r := range e.Results {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+// ErrorFormat controls how HandleError writes errors.
+type ErrorFormat int
+
+const (
+	// ErrorFormatText writes the error message as a single line of plain text.
+	ErrorFormatText ErrorFormat = iota
+
+	// ErrorFormatJSON writes the error as a JSON object with "error" and "kind" keys, for use by other programs.
+	ErrorFormatJSON
+)
+
+// HandleError writes err to w in the ErrorFormat of the command and returns err unchanged, so it can wrap the result
+// of Run or ParseAndRun. Nothing is written if err is nil.
+func (c *Command) HandleError(w io.Writer, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch c.ErrorFormat {
+	case ErrorFormatJSON:
+		_ = json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+			Kind  string `json:"kind"`
+		}{err.Error(), errorKind(err)})
+	default:
+		_, _ = fmt.Fprintln(w, err)
+	}
+
+	return err
+}
+
+// exitCode maps err to the exit code used by Execute.
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, ErrInvalidArguments), errors.As(err, new(flagParseError)):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// errorKind maps err to a stable identifier based on the errors defined by this package.
+func errorKind(err error) string {
+	var noExec NoExecError
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return "help"
+	case errors.Is(err, ErrInvalidArguments):
+		return "invalid_arguments"
+	case errors.Is(err, ErrUnparsed):
+		return "unparsed"
+	case errors.Is(err, ErrDuplicateCommand):
+		return "duplicate_command"
+	case errors.Is(err, ErrUnknownCommand):
+		return "unknown_command"
+	case errors.Is(err, ErrAmbiguousCommand):
+		return "ambiguous_command"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.As(err, &noExec):
+		return "no_exec"
+	default:
+		return "error"
+	}
+}
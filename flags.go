@@ -0,0 +1,277 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileBackedVar defines a string flag called name on fs that stores its value in p, along with a flag called
+// name-file that reads the value from a file instead, such as -token and -token-file. Keeping secrets in a file keeps
+// them out of the process list and shell history. The contents of the file are trimmed of surrounding whitespace,
+// and setting both flags is an error. EchoCommand echoes -name-file with the path of the file, and -name as REDACTED
+// rather than the secret, which may have been set from the environment or a config file.
+func FileBackedVar(fs *flag.FlagSet, p *string, name, usage string) {
+	v := &fileBacked{p: p, name: name}
+	fs.Var(fileBackedValue{v}, name, fmt.Sprintf("%s (or use -%s-file)", usage, name))
+	fs.Var(fileBackedPath{v}, name+"-file", fmt.Sprintf("read the value of -%s from `file`", name))
+}
+
+// fileBacked is the state shared by the two flags defined by FileBackedVar.
+type fileBacked struct {
+	p        *string
+	name     string
+	set      bool   // set directly with -name
+	filePath string // set from a file with -name-file
+}
+
+type fileBackedValue struct {
+	*fileBacked
+}
+
+func (v fileBackedValue) String() string {
+	if v.fileBacked == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v fileBackedValue) Type() string {
+	return "string"
+}
+
+func (v fileBackedValue) redacted() string {
+	return "REDACTED"
+}
+
+func (v fileBackedValue) Set(s string) error {
+	if v.filePath != "" {
+		return fmt.Errorf("cannot be used with -%s-file", v.name)
+	}
+	*v.p = s
+	v.set = true
+	return nil
+}
+
+type fileBackedPath struct {
+	*fileBacked
+}
+
+func (v fileBackedPath) String() string {
+	if v.fileBacked == nil {
+		return ""
+	}
+	return v.filePath
+}
+
+func (v fileBackedPath) Type() string {
+	return "file"
+}
+
+func (v fileBackedPath) Set(s string) error {
+	if v.set {
+		return fmt.Errorf("cannot be used with -%s", v.name)
+	}
+
+	data, err := os.ReadFile(s)
+	if err != nil {
+		return err
+	}
+	*v.p = strings.TrimSpace(string(data))
+	v.filePath = s
+	return nil
+}
+
+// ContainsHelpFlag reports whether args request help with -h or -help, with one or two dashes, without parsing them.
+// Args after a "--" terminator are not flags and are ignored. Any value given to a help flag, even -help=false,
+// still requests help, as the flag package returns flag.ErrHelp for it when the FlagSet does not define the flag.
+func ContainsHelpFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if name == "h" || name == "help" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitUnknownFlags removes the flags that are not defined in fs from the leading flags of args, returning the
+// remaining args and the removed flags. Like the flag package, scanning stops at the first non-flag arg or "--".
+// Values of unknown flags are only recognized in the -name=value form.
+func splitUnknownFlags(fs *flag.FlagSet, args []string) (known, unknown []string) {
+	known = make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			return append(known, args[i:]...), unknown
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil && name != "h" && name != "help" {
+			unknown = append(unknown, arg)
+			continue
+		}
+
+		known = append(known, arg)
+		if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			known = append(known, args[i])
+		}
+	}
+	return known, unknown
+}
+
+// expandShortFlags expands clusters of single letter flags in the leading flags of args, such as -abc, into separate
+// flags of fs, such as -a -b -c. Expansion of a cluster stops at the first flag that is not a bool, which takes the rest
+// of the cluster as its value, or the following arg if the cluster ends with it. Clusters that are the name of a flag
+// or contain a letter that is not a flag are left as they are.
+func expandShortFlags(fs *flag.FlagSet, args []string) []string {
+	expanded := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			return append(expanded, args[i:]...)
+		}
+
+		flags := []string{arg}
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if fs.Lookup(name) == nil && arg[1] != '-' && len(name) > 1 {
+			if cluster, ok := expandCluster(fs, arg[1:]); ok {
+				flags = cluster
+				name, _, hasValue = strings.Cut(flags[len(flags)-1][1:], "=")
+			}
+		}
+		expanded = append(expanded, flags...)
+
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			expanded = append(expanded, args[i])
+		}
+	}
+	return expanded
+}
+
+// expandCluster splits the cluster of single letter flags s into separate flags, reporting false if any letter is not
+// a flag of fs.
+func expandCluster(fs *flag.FlagSet, s string) ([]string, bool) {
+	var flags []string
+	for i, r := range s {
+		f := fs.Lookup(string(r))
+		if f == nil {
+			return nil, false
+		}
+
+		rest := s[i+len(string(r)):]
+		switch {
+		case strings.HasPrefix(rest, "="):
+			return append(flags, "-"+f.Name+rest), true
+		case !isBoolFlag(f) && rest != "":
+			return append(flags, "-"+f.Name+"="+rest), true
+		case !isBoolFlag(f):
+			return append(flags, "-"+f.Name), true
+		}
+		flags = append(flags, "-"+f.Name)
+	}
+	return flags, true
+}
+
+// EffectiveFlags returns a read-only copy of the flags that apply to the command selected by Parse, or c if it has
+// not been parsed, such as for contextual help or completion. Every command only parses the flags given before the
+// name of its subcommand, so the flags of ancestors are not included, nor are FlagAliases or a disabled -h.
+// The flags share the values of the originals, but setting them returns an error.
+func (c *Command) EffectiveFlags() *flag.FlagSet {
+	chain := c.selectedChain()
+	cmd := chain[len(chain)-1]
+
+	fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	if cmd.FlagSet == nil {
+		return fs
+	}
+
+	cmd.FlagSet.VisitAll(func(f *flag.Flag) {
+		if _, ok := cmd.FlagAliases[f.Name]; ok {
+			return
+		}
+		fs.Var(readOnlyValue{f}, f.Name, f.Usage)
+		fs.Lookup(f.Name).DefValue = f.DefValue
+	})
+	return fs
+}
+
+// readOnlyValue is the value of a flag returned by EffectiveFlags.
+type readOnlyValue struct {
+	f *flag.Flag
+}
+
+func (v readOnlyValue) String() string {
+	if v.f == nil {
+		return ""
+	}
+	return v.f.Value.String()
+}
+
+func (v readOnlyValue) Set(string) error {
+	return fmt.Errorf("flag -%s is read-only", v.f.Name)
+}
+
+func (v readOnlyValue) IsBoolFlag() bool {
+	return isBoolFlag(v.f)
+}
+
+func (v readOnlyValue) Type() string {
+	return flagType(v.f)
+}
+
+// lookupFlag returns the flag of c named name, including FlagAliases that are not yet registered with FlagSet.
+func (c *Command) lookupFlag(name string) *flag.Flag {
+	if c.FlagSet == nil {
+		return nil
+	}
+	if f := c.FlagSet.Lookup(name); f != nil {
+		return f
+	}
+	if canonical, ok := c.FlagAliases[name]; ok {
+		return c.FlagSet.Lookup(canonical)
+	}
+	return nil
+}
+
+// checkFlags checks that the leading flags of args are defined by c without setting them, returning the args that
+// follow the flags.
+func (c *Command) checkFlags(args []string) ([]string, error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[i+1:], nil
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return args[i:], nil
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		f := c.lookupFlag(name)
+		switch {
+		case f == nil && (name == "help" || name == "h" && !c.DisableHelpFlag), f == nil && c.CaptureUnknownFlags:
+			// help flags and captured unknown flags are accepted without a value
+		case f == nil:
+			return nil, fmt.Errorf("flag provided but not defined: -%s", name)
+		case !hasValue && !isBoolFlag(f):
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			i++
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,165 @@
+package scli
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileBackedVar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("  secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		Want       string
+		WantErr    bool
+	}{
+		{Name: "Default", PassedArgs: []string{}, Want: "default"},
+		{Name: "Flag", PassedArgs: []string{"-token", "abc"}, Want: "abc"},
+		{Name: "File", PassedArgs: []string{"-token-file", path}, Want: "secret"},
+		{Name: "Missing File", PassedArgs: []string{"-token-file", path + ".missing"}, WantErr: true},
+		{Name: "Both", PassedArgs: []string{"-token", "abc", "-token-file", path}, WantErr: true},
+		{Name: "Both File First", PassedArgs: []string{"-token-file", path, "-token", "abc"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			token := "default"
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			FileBackedVar(fs, &token, "token", "API token")
+
+			err := fs.Parse(tt.PassedArgs)
+			if (err != nil) != tt.WantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.WantErr)
+			}
+			if err == nil && token != tt.Want {
+				t.Errorf("token = %q, want %q", token, tt.Want)
+			}
+		})
+	}
+}
+
+func TestFileBackedVarEcho(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		Env        string
+		Want       string
+	}{
+		{Name: "File", PassedArgs: []string{"-token-file", path}, Want: "Running: root -token-file=" + path + "\n"},
+		{Name: "Environment", Env: "secret", Want: "Running: root -token=REDACTED\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if tt.Env != "" {
+				t.Setenv("APP_TOKEN", tt.Env)
+			}
+
+			var b strings.Builder
+			var token string
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			fs.SetOutput(&b)
+			FileBackedVar(fs, &token, "token", "API token")
+
+			cmd := Command{Usage: "root", FlagSet: fs, EnvPrefix: "APP", EchoCommand: true, Exec: returnsNil}
+			if err := cmd.ParseAndRun(context.Background(), tt.PassedArgs); err != nil {
+				t.Fatalf("ParseAndRun() error %v", err)
+			}
+			if token != "secret" || b.String() != tt.Want {
+				t.Errorf("token, echo = %q, %q, want %q, %q", token, b.String(), "secret", tt.Want)
+			}
+
+			if usage := cmd.UsageString(); !strings.Contains(usage, "-token string ") || !strings.Contains(usage, "-token-file file ") {
+				t.Errorf("UsageString() = %q, want the types of both flags", usage)
+			}
+		})
+	}
+}
+
+func TestCommand_EffectiveFlags(t *testing.T) {
+	rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = rootFlags.Bool("verbose", false, "verbose")
+	subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = subFlags.Int("count", 1, "count")
+	_ = subFlags.Bool("force", false, "force")
+
+	cmd := Command{
+		Usage:   "root",
+		FlagSet: rootFlags,
+		Subcommands: []*Command{{
+			Usage:           "sub",
+			FlagSet:         subFlags,
+			FlagAliases:     map[string]string{"n": "count"},
+			DisableHelpFlag: true,
+			Exec:            returnsNil,
+		}},
+	}
+
+	if fs := cmd.EffectiveFlags(); fs.Lookup("verbose") == nil {
+		t.Error("EffectiveFlags() before Parse is missing -verbose")
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"-verbose", "sub", "-count", "3"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	var names []string
+	fs := cmd.EffectiveFlags()
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	if want := []string{"count", "force"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("EffectiveFlags() = %v, want %v", names, want)
+	}
+
+	if f := fs.Lookup("count"); f.Value.String() != "3" || f.DefValue != "1" || !isBoolFlag(fs.Lookup("force")) {
+		t.Errorf("EffectiveFlags() count = %s default %s", f.Value, f.DefValue)
+	}
+	if err := fs.Set("count", "4"); err == nil {
+		t.Error("Set() error = nil, want error")
+	}
+}
+
+func TestContainsHelpFlag(t *testing.T) {
+	tests := []struct {
+		Name string
+		Args []string
+		Want bool
+	}{
+		{Name: "Short", Args: []string{"sub", "-h"}, Want: true},
+		{Name: "Long", Args: []string{"--help", "sub"}, Want: true},
+		{Name: "Explicit True", Args: []string{"-help=true"}, Want: true},
+		{Name: "Explicit False", Args: []string{"-h=false"}, Want: true},
+		{Name: "Long Explicit False", Args: []string{"-help=false"}, Want: true},
+		{Name: "Zero", Args: []string{"-h=0"}, Want: true},
+		{Name: "Double Dash Value", Args: []string{"--help=no"}, Want: true},
+		{Name: "After Terminator", Args: []string{"sub", "--", "-h"}},
+		{Name: "Similar Flag", Args: []string{"-host", "-helper"}},
+		{Name: "Triple Dash", Args: []string{"---help"}},
+		{Name: "None", Args: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if got := ContainsHelpFlag(tt.Args); got != tt.Want {
+				t.Errorf("ContainsHelpFlag(%q) = %v, want %v", tt.Args, got, tt.Want)
+			}
+		})
+	}
+}
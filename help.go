@@ -0,0 +1,138 @@
+package scli
+
+import (
+	"flag"
+	"strings"
+)
+
+// HelpModel is the content of the usage of a command split into its sections, for rendering help in a different
+// layout, such as in a TUI. The default usage is rendered from it.
+type HelpModel struct {
+	Usage       string           // the usage line, Usage or the name of the command followed by any ArgSpec
+	Description string           // LongHelp, or ShortHelp if there is no LongHelp, unwrapped
+	Subcommands []HelpSubcommand // in the order of Subcommands, without disabled ones
+	Flags       []HelpFlag       // in name order followed by the help flag, without FlagAliases
+	Examples    []string
+
+	// InheritedFlags are the flags of the ancestors of the command from the root down, without their help flags.
+	// Only included when ShowInheritedFlags is set on the command or an ancestor.
+	InheritedFlags []HelpFlag
+}
+
+// HelpSubcommand describes a subcommand in a HelpModel. Names have any SubcommandNamePrefix of the parent removed.
+type HelpSubcommand struct {
+	Name      string
+	Aliases   []string
+	ShortHelp string
+}
+
+// HelpFlag describes a flag in a HelpModel.
+type HelpFlag struct {
+	Name    string
+	Type    string // the name of the type of value the flag accepts, as rendered for empty defaults, empty for bools
+	Default string
+	Usage   string // with the backquotes around the name of the type of value removed, as by flag.UnquoteUsage
+	IsBool  bool
+}
+
+// HelpSections returns the content of the usage of c, including the help flag unless it is disabled.
+func (c *Command) HelpSections() HelpModel {
+	m := c.messages()
+	h := HelpModel{
+		Usage:       c.Usage,
+		Description: c.LongHelp,
+		Examples:    append([]string(nil), c.Examples...),
+	}
+	if h.Usage == "" {
+		h.Usage = c.Name()
+	}
+	for _, arg := range c.ArgSpec {
+		h.Usage += " " + arg.String()
+	}
+	if h.Description == "" {
+		h.Description = c.ShortHelp
+	}
+
+	for _, sub := range c.Subcommands {
+		if !c.subcommandEnabled(sub) {
+			continue
+		}
+		entry := HelpSubcommand{
+			Name:      strings.TrimPrefix(sub.Name(), c.SubcommandNamePrefix),
+			ShortHelp: sub.ShortHelp,
+		}
+		for _, alias := range sub.Aliases {
+			entry.Aliases = append(entry.Aliases, strings.TrimPrefix(alias, c.SubcommandNamePrefix))
+		}
+		h.Subcommands = append(h.Subcommands, entry)
+	}
+
+	h.Flags = c.helpFlags()
+
+	var ancestors []*Command
+	show := c.ShowInheritedFlags
+	for p := c.parent; p != nil; p = p.parent {
+		ancestors = append([]*Command{p}, ancestors...)
+		show = show || p.ShowInheritedFlags
+	}
+	if show {
+		for _, p := range ancestors {
+			h.InheritedFlags = append(h.InheritedFlags, p.helpFlags()...)
+		}
+	}
+
+	help := HelpFlag{Name: "h", Default: "false", Usage: m.HelpFlag, IsBool: true}
+	switch {
+	case !c.DisableHelpFlag:
+		h.Flags = append(h.Flags, help)
+	case c.FlagSet == nil || c.FlagSet.Lookup("help") == nil:
+		help.Name = "help"
+		h.Flags = append(h.Flags, help)
+	}
+	return h
+}
+
+// summaryWidth is the number of runes Summary truncates to, to fit a terminal line.
+const summaryWidth = 80
+
+// Summary returns a one line description of c as its name and ShortHelp separated by a dash, such as
+// "sync — sync things", or just the name without a ShortHelp. It is truncated to 80 runes.
+func (c *Command) Summary() string {
+	if c.ShortHelp == "" {
+		return truncateText(c.Name(), summaryWidth)
+	}
+	return truncateText(c.Name()+" — "+c.ShortHelp, summaryWidth)
+}
+
+// ListCommands returns the Summary of each of the Subcommands of c that is enabled, in order.
+func (c *Command) ListCommands() []string {
+	subs := c.enabledSubcommands()
+	summaries := make([]string, len(subs))
+	for i, sub := range subs {
+		summaries[i] = sub.Summary()
+	}
+	return summaries
+}
+
+// helpFlags describes the flags of FlagSet, without FlagAliases or the help flag.
+func (c *Command) helpFlags() []HelpFlag {
+	if c.FlagSet == nil {
+		return nil
+	}
+
+	var flags []HelpFlag
+	c.FlagSet.VisitAll(func(f *flag.Flag) {
+		if _, ok := c.FlagAliases[f.Name]; ok {
+			return
+		}
+
+		flags = append(flags, HelpFlag{
+			Name:    f.Name,
+			Type:    flagType(f),
+			Default: f.DefValue,
+			Usage:   flagUsage(f),
+			IsBool:  isBoolFlag(f),
+		})
+	})
+	return flags
+}
@@ -0,0 +1,110 @@
+package scli
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommand_HelpSections(t *testing.T) {
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = fs.String("name", "", "the name")
+	_ = fs.Bool("force", false, "force it")
+	_ = fs.String("dir", "", "search `path` for files")
+
+	cmd := Command{
+		Usage:                "mytool [flags] <name>",
+		ShortHelp:            "short help",
+		LongHelp:             "long help",
+		FlagSet:              fs,
+		FlagAliases:          map[string]string{"n": "name"},
+		SubcommandNamePrefix: "mytool-",
+		Examples:             []string{"mytool -name foo"},
+		Subcommands: []*Command{
+			{Usage: "mytool-sync", Aliases: []string{"mytool-s"}, ShortHelp: "sync things"},
+		},
+	}
+
+	want := HelpModel{
+		Usage:       "mytool [flags] <name>",
+		Description: "long help",
+		Subcommands: []HelpSubcommand{{Name: "sync", Aliases: []string{"s"}, ShortHelp: "sync things"}},
+		Flags: []HelpFlag{
+			{Name: "dir", Type: "path", Usage: "search path for files"},
+			{Name: "force", Default: "false", Usage: "force it", IsBool: true},
+			{Name: "name", Type: "string", Usage: "the name"},
+			{Name: "h", Default: "false", Usage: DefaultMessages.HelpFlag, IsBool: true},
+		},
+		Examples: []string{"mytool -name foo"},
+	}
+
+	if got := cmd.HelpSections(); !reflect.DeepEqual(got, want) {
+		t.Errorf("HelpSections() = %+v, want %+v", got, want)
+	}
+
+	if usage := cmd.UsageString(); !strings.Contains(usage, "  -dir path     search path for files\n") {
+		t.Errorf("UsageString() = %q, want the flag usage without backquotes", usage)
+	}
+}
+
+func TestCommand_ShowInheritedFlags(t *testing.T) {
+	rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = rootFlags.Bool("verbose", false, "verbose output")
+	dbFlags := flag.NewFlagSet("db", flag.ContinueOnError)
+	_ = dbFlags.String("dsn", "", "the database")
+	migrateFlags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	_ = migrateFlags.Int("steps", 1, "the steps")
+
+	migrate := &Command{Usage: "migrate", FlagSet: migrateFlags, Exec: returnsNil}
+	cmd := &Command{
+		Usage:              "root",
+		FlagSet:            rootFlags,
+		ShowInheritedFlags: true,
+		Subcommands: []*Command{{
+			Usage:       "db",
+			FlagSet:     dbFlags,
+			Subcommands: []*Command{migrate},
+		}},
+	}
+
+	if err := cmd.Parse([]string{"db", "migrate"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+
+	want := `USAGE
+ migrate
+
+FLAGS
+  -steps 1  the steps
+  -h        prints help and usage for this command or subcommand
+
+INHERITED FLAGS
+  -verbose     verbose output
+  -dsn string  the database
+`
+	if got := migrate.UsageString(); got != want {
+		t.Errorf("UsageString() = %q, want %q", got, want)
+	}
+
+	cmd.ShowInheritedFlags = false
+	if h := migrate.HelpSections(); h.InheritedFlags != nil {
+		t.Errorf("InheritedFlags = %+v, want nil", h.InheritedFlags)
+	}
+}
+
+func TestCommand_ListCommands(t *testing.T) {
+	cmd := Command{
+		Usage: "mytool",
+		Subcommands: []*Command{
+			{Usage: "sync [flags]", ShortHelp: "sync things"},
+			{Usage: "clean"},
+			{Usage: "long", ShortHelp: strings.Repeat("a", 100)},
+		},
+	}
+
+	want := []string{"sync — sync things", "clean", "long — " + strings.Repeat("a", 72) + "…"}
+	if got := cmd.ListCommands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListCommands() = %q, want %q", got, want)
+	}
+}
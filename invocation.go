@@ -0,0 +1,103 @@
+package scli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Invocation is a fully resolved invocation of a command tree, as recorded by CaptureInvocation after parsing. It can
+// be serialized, for example as JSON in a bug report, and run again later with Replay.
+type Invocation struct {
+	Commands []InvokedCommand `json:"commands"`       // the commands from the root down to the selected command
+	Args     []string         `json:"args,omitempty"` // the positional args of the selected command
+}
+
+// InvokedCommand is a command of an Invocation along with the flags that were set on it.
+type InvokedCommand struct {
+	Name  string            `json:"name"`
+	Flags map[string]string `json:"flags,omitempty"` // flags explicitly set, including from the environment
+}
+
+// CaptureInvocation records the commands selected by Parse from c down, along with the flags set on each of them and
+// the positional args. Flags set with one of FlagAliases are recorded under the name of the flag they refer to.
+// Returns an empty Invocation if c has not been parsed.
+func (c *Command) CaptureInvocation() Invocation {
+	if c.selected == nil {
+		return Invocation{}
+	}
+
+	chain := c.selectedChain()
+	inv := Invocation{
+		Commands: make([]InvokedCommand, len(chain)),
+		Args:     append([]string(nil), chain[len(chain)-1].args...),
+	}
+
+	for i, cmd := range chain {
+		inv.Commands[i].Name = cmd.Name()
+		if cmd.FlagSet == nil {
+			continue
+		}
+
+		cmd.FlagSet.Visit(func(f *flag.Flag) {
+			if inv.Commands[i].Flags == nil {
+				inv.Commands[i].Flags = make(map[string]string)
+			}
+			name := f.Name
+			if canonical, ok := cmd.FlagAliases[name]; ok {
+				name = canonical
+			}
+			inv.Commands[i].Flags[name] = f.Value.String()
+		})
+	}
+	return inv
+}
+
+// Replay parses and runs c with the args reconstructed from inv, discarding the selections of any previous parse.
+// The first command of inv must be c, and an ErrUnknownCommand is returned if any of the others is not found.
+// Flags keep any value set by a previous parse unless inv sets them, so a fresh command tree gives the most faithful
+// reproduction.
+func (c *Command) Replay(ctx context.Context, inv Invocation) error {
+	if len(inv.Commands) == 0 || inv.Commands[0].Name != c.Name() {
+		return fmt.Errorf("%w: invocation is not of %q", ErrUnknownCommand, c.Name())
+	}
+
+	// recorded names keep the parent's SubcommandNamePrefix, which is not typed on the command line
+	path := make([]string, 0, len(inv.Commands)-1)
+	chain := []*Command{c}
+	for _, cmd := range inv.Commands[1:] {
+		parent := chain[len(chain)-1]
+		name := strings.TrimPrefix(cmd.Name, parent.SubcommandNamePrefix)
+		sub := parent.subcommand(name)
+		if sub == nil {
+			return fmt.Errorf("%w: %q for %q", ErrUnknownCommand, name, commandPath(chain))
+		}
+		path = append(path, name)
+		chain = append(chain, sub)
+	}
+
+	var args []string
+	for i, cmd := range inv.Commands {
+		if i > 0 {
+			args = append(args, path[i-1])
+		}
+
+		names := make([]string, 0, len(cmd.Flags))
+		for name := range cmd.Flags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			args = append(args, fmt.Sprintf("-%s=%s", name, cmd.Flags[name]))
+		}
+	}
+	if len(inv.Args) > 0 {
+		args = append(append(args, "--"), inv.Args...)
+	}
+
+	c.reset()
+	return c.ParseAndRun(ctx, args)
+}
@@ -0,0 +1,109 @@
+package scli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestCommand_Replay(t *testing.T) {
+	var got []string
+	newCommand := func() *Command {
+		rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+		verbose := rootFlags.Bool("verbose", false, "verbose")
+		migrateFlags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+		steps := migrateFlags.Int("steps", 0, "steps")
+
+		return &Command{
+			Usage:   "root",
+			FlagSet: rootFlags,
+			Subcommands: []*Command{{
+				Usage: "db",
+				Subcommands: []*Command{{
+					Usage:       "migrate",
+					FlagSet:     migrateFlags,
+					FlagAliases: map[string]string{"n": "steps"},
+					Exec: func(ctx context.Context, args []string) error {
+						got = args
+						if !*verbose || *steps != 2 {
+							return errors.New("flags not replayed")
+						}
+						return nil
+					},
+				}},
+			}},
+		}
+	}
+
+	cmd := newCommand()
+	if err := cmd.Parse([]string{"-verbose", "db", "migrate", "-n", "2", "up", "-down"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+
+	inv := cmd.CaptureInvocation()
+	want := Invocation{
+		Commands: []InvokedCommand{
+			{Name: "root", Flags: map[string]string{"verbose": "true"}},
+			{Name: "db"},
+			{Name: "migrate", Flags: map[string]string{"steps": "2"}},
+		},
+		Args: []string{"up", "-down"},
+	}
+	if !reflect.DeepEqual(inv, want) {
+		t.Fatalf("CaptureInvocation() = %+v, want %+v", inv, want)
+	}
+
+	data, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatalf("Marshal() error %v", err)
+	}
+	var decoded Invocation
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error %v", err)
+	}
+
+	if err := newCommand().Replay(context.Background(), decoded); err != nil {
+		t.Fatalf("Replay() error %v", err)
+	}
+	if want := []string{"up", "-down"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %v, want %v", got, want)
+	}
+
+	decoded.Commands[1].Name = "nope"
+	if err := newCommand().Replay(context.Background(), decoded); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Replay() error = %v, want %v", err, ErrUnknownCommand)
+	}
+
+	var ran bool
+	prefixed := func() *Command {
+		return &Command{
+			Usage:                "tool",
+			SubcommandNamePrefix: "tool-",
+			Subcommands: []*Command{{
+				Usage: "tool-foo",
+				Exec: func(ctx context.Context, args []string) error {
+					ran = true
+					return nil
+				},
+			}},
+		}
+	}
+
+	cmd = prefixed()
+	if err := cmd.Parse([]string{"foo"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+	inv = cmd.CaptureInvocation()
+	if name := inv.Commands[1].Name; name != "tool-foo" {
+		t.Fatalf("CaptureInvocation() name = %q, want %q", name, "tool-foo")
+	}
+	if err := prefixed().Replay(context.Background(), inv); err != nil {
+		t.Fatalf("Replay() error %v", err)
+	}
+	if !ran {
+		t.Error("Replay() did not run the prefixed subcommand")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package scli
+
+import (
+	"errors"
+	"os"
+)
+
+// acquireLock creates the file at path exclusively on platforms without flock or LockFileEx, removing it when
+// released. A process that exits without releasing the lock leaves the file behind, which must be removed by hand.
+func acquireLock(path string) (release func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, ErrAlreadyRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+
+	return func() {
+		_ = os.Remove(path)
+	}, nil
+}
@@ -0,0 +1,43 @@
+//go:build windows
+
+package scli
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errorLockViolation syscall.Errno = 33
+)
+
+// acquireLock takes an exclusive LockFileEx lock on the file at path, creating it if needed. The lock is released by
+// the returned func, or by the OS if the process exits, so a crash never leaves a stale lock.
+func acquireLock(path string) (release func(), err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlapped syscall.Overlapped
+	r, _, lockErr := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0,
+		uintptr(unsafe.Pointer(&overlapped)))
+	if r == 0 {
+		_ = f.Close()
+		if errors.Is(lockErr, errorLockViolation) {
+			return nil, ErrAlreadyRunning
+		}
+		return nil, lockErr
+	}
+
+	return func() {
+		_ = f.Close() // closing the handle releases the lock
+	}, nil
+}
@@ -0,0 +1,108 @@
+package scli
+
+import (
+	"fmt"
+)
+
+// Messages holds the text used by the default usage output and the errors built by Parse, so they can be translated.
+// Any field left empty falls back to the matching field of DefaultMessages.
+type Messages struct {
+	// Usage, Subcommands, Flags, InheritedFlags and Examples are the section headers of the default usage output.
+	Usage          string
+	Subcommands    string
+	Flags          string
+	InheritedFlags string
+	Examples       string
+
+	// HelpFlag is the usage line of the automatic help flag.
+	HelpFlag string
+
+	// InvalidArguments is a format string for errors returned by an ArgsValidator, with a single %s verb that
+	// receives the validators message.
+	InvalidArguments string
+
+	// UnknownSubcommand is a format string appended to InvalidArguments errors when the ArgsValidator of a command
+	// with Subcommands rejects only its first positional arg. It receives that arg and the command name as %q and %s
+	// verbs.
+	UnknownSubcommand string
+}
+
+// DefaultMessages are the English messages used when a Command does not provide its own.
+var DefaultMessages = Messages{
+	Usage:             "USAGE",
+	Subcommands:       "SUBCOMMANDS",
+	Flags:             "FLAGS",
+	InheritedFlags:    "INHERITED FLAGS",
+	Examples:          "EXAMPLES",
+	HelpFlag:          "prints help and usage for this command or subcommand",
+	InvalidArguments:  "invalid arguments: %s",
+	UnknownSubcommand: "%q may be a misspelled or missing subcommand of %s",
+}
+
+// messages returns the Messages of c, or of its nearest ancestor with any, with empty fields filled from
+// DefaultMessages.
+func (c *Command) messages() Messages {
+	m := DefaultMessages
+	own := c.Messages
+	for cmd := c.parent; own == nil && cmd != nil; cmd = cmd.parent {
+		own = cmd.Messages
+	}
+	if own == nil {
+		return m
+	}
+
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&m.Usage, own.Usage},
+		{&m.Subcommands, own.Subcommands},
+		{&m.Flags, own.Flags},
+		{&m.InheritedFlags, own.InheritedFlags},
+		{&m.Examples, own.Examples},
+		{&m.HelpFlag, own.HelpFlag},
+		{&m.InvalidArguments, own.InvalidArguments},
+		{&m.UnknownSubcommand, own.UnknownSubcommand},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	return m
+}
+
+// invalidArguments prints the usage of c, or calls OnValidationError if it is set, and wraps the error returned by an
+// ArgsValidator in an InvalidArgumentsError, using the InvalidArguments message of c.
+func (c *Command) invalidArguments(err error) error {
+	return c.reportInvalidArguments(err, err.Error())
+}
+
+// argsRejected is invalidArguments for an error of validator on the args of c. When validator would accept the args
+// without the first, that arg did not select a subcommand and is likely a typo of one, which the message notes.
+func (c *Command) argsRejected(validator ArgsValidator, err error) error {
+	msg := err.Error()
+	if len(c.Subcommands) > 0 && len(c.args) > 0 && validator(c.args[1:]) == nil {
+		msg += " (" + fmt.Sprintf(c.messages().UnknownSubcommand, c.args[0], c.Name()) + ")"
+	}
+	return c.reportInvalidArguments(err, msg)
+}
+
+func (c *Command) reportInvalidArguments(err error, msg string) error {
+	err = InvalidArgumentsError{Err: err, msg: fmt.Sprintf(c.messages().InvalidArguments, msg)}
+	if onValidationError := c.onValidationError(); onValidationError != nil {
+		onValidationError(c, err)
+	} else {
+		c.FlagSet.Usage()
+	}
+	return err
+}
+
+// onValidationError returns the OnValidationError of c, or of its nearest ancestor with one.
+func (c *Command) onValidationError() func(c *Command, err error) {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.OnValidationError != nil {
+			return cmd.OnValidationError
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package scli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat is a format commands can write their results in, selected with the flag added by RegisterOutputFlag
+// and written with Render. It implements flag.Value, accepting any of the formats regardless of case.
+type OutputFormat string
+
+const (
+	// OutputTable writes results as aligned columns for people to read.
+	OutputTable OutputFormat = "table"
+	// OutputJSON writes results as indented JSON.
+	OutputJSON OutputFormat = "json"
+	// OutputYAML writes results as YAML.
+	OutputYAML OutputFormat = "yaml"
+)
+
+var outputFormats = []OutputFormat{OutputTable, OutputJSON, OutputYAML}
+
+func (f *OutputFormat) String() string {
+	return string(*f)
+}
+
+// Set parses s as one of the output formats.
+func (f *OutputFormat) Set(s string) error {
+	for _, format := range outputFormats {
+		if strings.EqualFold(s, string(format)) {
+			*f = format
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, must be one of %s", s, joinFormats())
+}
+
+// Type is the name of the value in the usage.
+func (f *OutputFormat) Type() string {
+	return "format"
+}
+
+// RegisterOutputFlag defines an -output flag on fs that sets p to the chosen format, defaulting to def.
+func RegisterOutputFlag(fs *flag.FlagSet, p *OutputFormat, def OutputFormat) {
+	*p = def
+	fs.Var(p, "output", "output format, one of "+joinFormats())
+}
+
+// Render writes v to w in format.
+// Tables are written with a row for each element of a slice or array, and a header of the field names of struct
+// elements or keys of map elements. A struct or map that is not in a slice is written as a row for each field or key,
+// and any other value is written as is.
+func Render(w io.Writer, format OutputFormat, v any) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case OutputTable:
+		tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+		renderTable(tw, reflect.ValueOf(v))
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, must be one of %s", format, joinFormats())
+	}
+}
+
+//goland:noinspection GoUnhandledErrorResult
+func renderTable(w io.Writer, v reflect.Value) {
+	v = indirect(v)
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var header []string
+		if v.Len() > 0 {
+			header = tableHeader(indirect(v.Index(0)))
+		}
+		if len(header) > 0 {
+			fmt.Fprintln(w, strings.ToUpper(strings.Join(header, "\t")))
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			elem := indirect(v.Index(i))
+			if len(header) == 0 {
+				fmt.Fprintln(w, formatCell(elem))
+				continue
+			}
+
+			cells := make([]string, len(header))
+			for j, name := range header {
+				cells[j] = formatCell(tableCell(elem, name))
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+	case reflect.Struct, reflect.Map:
+		for _, name := range tableHeader(v) {
+			fmt.Fprintf(w, "%s:\t%s\n", name, formatCell(tableCell(v, name)))
+		}
+	default:
+		fmt.Fprintln(w, formatCell(v))
+	}
+}
+
+// tableHeader returns the column names of v, the names of the exported fields of a struct or sorted keys of a map.
+// Fields use the name from their json tag if they have one, and fields tagged "-" are skipped.
+func tableHeader(v reflect.Value) []string {
+	var names []string
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if name, ok := fieldName(v.Type().Field(i)); ok {
+				names = append(names, name)
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			names = append(names, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(names)
+	}
+	return names
+}
+
+// tableCell returns the field or map entry of v named name.
+func tableCell(v reflect.Value, name string) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if n, ok := fieldName(v.Type().Field(i)); ok && n == name {
+				return v.Field(i)
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if fmt.Sprint(key.Interface()) == name {
+				return v.MapIndex(key)
+			}
+		}
+	}
+	return reflect.Value{}
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	default:
+		return name, true
+	}
+}
+
+func formatCell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// indirect follows pointers and interfaces to the value they refer to, returning the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func joinFormats() string {
+	names := make([]string, len(outputFormats))
+	for i, format := range outputFormats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
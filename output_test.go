@@ -0,0 +1,91 @@
+package scli
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestRegisterOutputFlag(t *testing.T) {
+	var format OutputFormat
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	RegisterOutputFlag(fs, &format, OutputTable)
+
+	if format != OutputTable {
+		t.Errorf("format = %q, want %q", format, OutputTable)
+	}
+
+	if err := fs.Parse([]string{"-output", "JSON"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+	if format != OutputJSON {
+		t.Errorf("format = %q, want %q", format, OutputJSON)
+	}
+
+	if err := format.Set("xml"); err == nil {
+		t.Error("Set() error = nil, want error")
+	}
+}
+
+func TestRender(t *testing.T) {
+	type item struct {
+		Name   string `json:"name"`
+		Size   int    `json:"size"`
+		secret string
+	}
+	items := []item{{Name: "a", Size: 1}, {Name: "bb", Size: 22, secret: "x"}}
+
+	tests := []struct {
+		Name   string
+		Format OutputFormat
+		Value  any
+		Want   string
+	}{
+		{
+			Name:   "JSON",
+			Format: OutputJSON,
+			Value:  items[0],
+			Want:   "{\n  \"name\": \"a\",\n  \"size\": 1\n}\n",
+		},
+		{
+			Name:   "YAML",
+			Format: OutputYAML,
+			Value:  map[string]int{"a": 1},
+			Want:   "a: 1\n",
+		},
+		{
+			Name:   "Table Slice",
+			Format: OutputTable,
+			Value:  items,
+			Want:   "NAME  SIZE\na     1\nbb    22\n",
+		},
+		{
+			Name:   "Table Struct",
+			Format: OutputTable,
+			Value:  &items[1],
+			Want:   "name:  bb\nsize:  22\n",
+		},
+		{
+			Name:   "Table Strings",
+			Format: OutputTable,
+			Value:  []string{"a", "b"},
+			Want:   "a\nb\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Render(&b, tt.Format, tt.Value); err != nil {
+				t.Fatalf("Render() error %v", err)
+			}
+			if b.String() != tt.Want {
+				t.Errorf("Render() = %q, want %q", b.String(), tt.Want)
+			}
+		})
+	}
+
+	if err := Render(&strings.Builder{}, "xml", items); err == nil {
+		t.Error("Render() error = nil, want error")
+	}
+}
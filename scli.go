@@ -1,14 +1,27 @@
 package scli
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
+	"unicode/utf8"
 )
 
+const defaultMaxDepth = 64
+
+// ExecFunc is the signature of the function that executes a Command.
+type ExecFunc func(ctx context.Context, args []string) error
+
 type Command struct {
 	// Usage is a one-liner usage message. First word of Usage is used for the Command's name.
 	// Required for sub-commands.
@@ -17,10 +30,25 @@ type Command struct {
 	//     cmd [flags] subcmd [flags] <required> [<optional> ...]
 	Usage string
 
+	// NameOverride is the name of the command when it differs from the first word of Usage, such as when Usage
+	// begins with the full command path. Optional.
+	NameOverride string
+
+	// Enabled reports whether the command is available, for gating subcommands behind runtime feature flags. When it
+	// returns false the command is hidden from the usage, ListCommands, completion and RunShell listing of its parent,
+	// and naming it, even by a prefix for LookupCommandPrefix, is an unknown subcommand error. It receives the context
+	// passed to ParseContext or ParseAndRun. Optional, commands are enabled by default.
+	Enabled func(ctx context.Context) bool
+
 	// Aliases is a slice of alternate names that can be used for a sub command instead of the first word of usage.
 	// Optional.
 	Aliases []string
 
+	// AliasCommands maps names that are replaced by a sequence of args when they are the first arg after the flags of
+	// this command, such as "deploy-prod" for {"deploy", "-env", "prod"}, so an alias can preset flags and
+	// subcommands. Subcommands take precedence over AliasCommands of the same name. Optional.
+	AliasCommands map[string][]string
+
 	// ShortHelp is a short description that is displayed in the global help -h output.
 	// Optional, but recommended.
 	ShortHelp string
@@ -29,37 +57,298 @@ type Command struct {
 	// If not provided ShortHelp will be used in its place. Optional.
 	LongHelp string
 
+	// Annotations holds arbitrary metadata about the command, such as a category or whether it requires
+	// authentication, for use by middleware, hooks and documentation tools. It is not used by this package. Optional.
+	Annotations map[string]string
+
+	// Examples are complete example invocations, beginning with the name of the root command, that are listed in
+	// the usage. ValidateExamples can be used to check that they still parse. Optional.
+	Examples []string
+
 	// Subcommands is a slice of commands supported by Command.
 	// Subcommands are optional and only needed if you application needs multiple commands.
 	Subcommands []*Command
 
+	// UnknownSubcommandIsError makes Parse return an UnknownSubcommandError, with suggestions of similarly named
+	// subcommands, when the first arg does not select a subcommand, rather than passing it to Exec as a positional
+	// arg. An unknown arg following a "--" separator is still passed to Exec, while one naming a subcommand still
+	// selects it. Optional.
+	UnknownSubcommandIsError bool
+
+	// SubcommandNamePrefix is removed from the names and aliases of Subcommands when matching them and listing them
+	// in the usage, so a subcommand named mytool-foo is invoked as foo. Optional.
+	SubcommandNamePrefix string
+
+	// BuildInfo is printed by the version subcommand added by EnableVersionCommand. Optional.
+	BuildInfo BuildInfo
+
+	// EnableVersionCommand adds a version subcommand when the command is parsed, unless it already has one.
+	// It prints BuildInfo along with the Go version, OS and architecture, or just the version with -short. Optional.
+	EnableVersionCommand bool
+
+	// EnableArgv0Dispatch makes Parse select the subcommand named by the program the process was invoked as, like
+	// busybox, so a link to the binary named after a subcommand runs it directly. The base name of os.Args[0] is
+	// matched against the names and aliases of Subcommands, ignoring any .exe extension, and the args are parsed by
+	// the matching subcommand. When nothing matches the args are parsed as normal. Only used on the root. Optional.
+	EnableArgv0Dispatch bool
+
 	// UsageFunc allows a custom function to be provided for printing usage instructions for the current command.
 	// Optional, defaultUsageFunc will be used if none is provided.
 	UsageFunc func(c *Command) string
 
 	// FlagSet for this command. Optional, but if none is provided,
 	// an empty FlagSet will be defined to ensure -h works as expected.
+	// Each command must have its own FlagSet, Parse returns an ErrSharedFlagSet if any two commands in the tree
+	// share one.
 	FlagSet *flag.FlagSet
 
+	// FlagErrorHandling is the error handling of the FlagSet defined when none is provided.
+	// Optional, defaults to flag.ContinueOnError so errors are returned rather than exiting the program.
+	FlagErrorHandling flag.ErrorHandling
+
+	// PreserveFlagErrorHandling keeps the error handling of a provided FlagSet. Otherwise Parse changes it to
+	// flag.ContinueOnError, so that a FlagSet created with flag.ExitOnError does not exit the program. Optional.
+	PreserveFlagErrorHandling bool
+
+	// EnvPrefix enables setting flags that were not passed on the command line from environment variables.
+	// The prefix of a command joins the EnvPrefix of each command from the root down with underscores, skipping
+	// empty ones, so a root with APP and a subcommand with DB read the flag max-conns from APP_DB_MAX_CONNS.
+	// Flags are only read from the environment when the joined prefix is not empty. Optional.
+	EnvPrefix string
+
+	// ConfigFile is the path of a config file, in one of the formats described by LoadConfig, that Parse sets the
+	// flags of the selected commands from when they were not set on the command line or from the environment. The
+	// values are set before FlagValidators and AtLeastOneFlag are checked, so they are validated like any other flag
+	// and can satisfy AtLeastOneFlag. Only used on the root command. Optional.
+	ConfigFile string
+
+	// LaxConfig ignores keys that do not refer to a flag of the command tree when loading a config file with
+	// ConfigFile or LoadConfig, instead of returning an error. Only used on the root command or the command
+	// LoadConfig is called on. Optional.
+	LaxConfig bool
+
+	// HelpWidth is the width the description in the default usage is wrapped to, and the ShortHelp of subcommands is
+	// truncated to. When 0 the width of the terminal FlagSet writes to is used, falling back to 80 columns if it is not
+	// a terminal. Negative values disable wrapping and truncation. Optional.
+	HelpWidth int
+
+	// PreserveUsageWhitespace prints the usage exactly as returned by UsageFunc, instead of trimming the surrounding
+	// whitespace of the default usage and ending it with a blank line. Useful when a custom UsageFunc relies on exact
+	// spacing. Optional.
+	PreserveUsageWhitespace bool
+
+	// HideEmptyDefaults omits the default value from the usage of flags whose default is empty, rather than
+	// rendering the type of the flag in its place. Optional.
+	HideEmptyDefaults bool
+
+	// FlagAliases maps old flag names to the name of the flag in FlagSet that replaced them, so both can be used
+	// during a deprecation period. Aliases share the value of their flag, are hidden from the usage, and print a
+	// deprecation notice when used. Optional.
+	FlagAliases map[string]string
+
+	// FailOnDeprecated makes using any of FlagAliases on this command or its subcommands an error wrapped by
+	// ErrInvalidArguments instead of a printed deprecation notice, such as to enforce migrating scripts in CI.
+	// Optional.
+	FailOnDeprecated bool
+
+	// CaptureUnknownFlags records flags that are not defined in FlagSet instead of failing to parse them, so they can
+	// be forwarded to another program through UnknownFlags. Values of unknown flags must be passed in the -name=value
+	// form, as there is no way to tell whether an unknown flag takes a value. Optional.
+	CaptureUnknownFlags bool
+
+	// StopAtSubcommand stops parsing the flags of this command at the first arg that names a subcommand, which then
+	// receives every arg after its name as positional args without parsing any flags, for commands that dispatch to
+	// other programs. Flag values that name a subcommand must use the -name=value form. Optional.
+	StopAtSubcommand bool
+
+	// CombinedShortFlags allows single letter bool flags to be combined in a single argument, so -abc is parsed as
+	// -a -b -c. The first flag of a combination that is not a bool ends it, taking the rest of the argument as its
+	// value, so -vo=out and -vout are both -v -o=out. Single letter FlagAliases can also be combined. An argument that
+	// is the name of a flag, or contains a letter that is not a flag, is parsed as is. Optional.
+	CombinedShortFlags bool
+
+	// FlagValidators maps flag names to functions that validate their value. After parsing, the validator of each
+	// flag that was set is called with its value, and any error is wrapped by an ErrInvalidArguments. Optional.
+	FlagValidators map[string]func(value string) error
+
+	// AtLeastOneFlag lists groups of flag names, of which at least one flag must be set in each group, such as
+	// [][]string{{"file", "url"}}. After parsing, a group with none of its flags set is reported with an error wrapped
+	// by ErrInvalidArguments. Flags set from the environment count as set, and empty groups are ignored. Optional.
+	AtLeastOneFlag [][]string
+
+	// ArgsTransform allows the arguments for this command to be rewritten before they are parsed, for example to map a
+	// renamed flag to its new name. The returned slice is what gets parsed. Optional.
+	ArgsTransform func(args []string) []string
+
+	// DisableHelpFlag stops -h from being treated as a request for help, so FlagSet can define its own -h flag.
+	// If FlagSet does not define -h it is rejected like any other undefined flag.
+	// -help is still honored and listed in the usage, unless FlagSet also defines a help flag. Optional.
+	DisableHelpFlag bool
+
+	// RequireArgSeparator makes Parse return an ErrInvalidArguments unless positional args are separated from flags
+	// by "--", as in 'cmd -flag value -- args...'. Useful for commands that pass their args on to other programs.
+	// Optional.
+	RequireArgSeparator bool
+
+	// ArgDefaults are default values for positional args. When fewer args than ArgDefaults are passed, the missing
+	// trailing args are filled from ArgDefaults before ArgsValidator is called, so ExactArgs(len(ArgDefaults)) accepts
+	// any number of args up to len(ArgDefaults). Optional.
+	ArgDefaults []string
+
 	// ArgsValidator provides a validation function for arguments. There are multiple builtin validators as the
 	// XArgs functions in this package.
 	// Any error returned by ArgsValidator gets wrapped by an ErrInvalidArguments then is returned by Run or ParseAndRun.
 	// When ArgsValidator returns an error the commands usage will be printed as well as the body of the error message.
 	ArgsValidator ArgsValidator
 
+	// ArgSpec describes the positional args of the command, which are appended to Usage in the default usage, as in
+	// "<src> [dest] [extra...]", so Usage should only hold the name and any flags. When ArgsValidator is not set, the
+	// number of args is validated against it, requiring at least the Required args and, unless the last is Variadic,
+	// at most one arg for each ArgDef. Optional.
+	ArgSpec []ArgDef
+
+	// RootOnlyFlags names flags that may only be defined on the root command. Parse and Validate return an error if
+	// any other command in the tree defines one. Only read from the root command. Optional.
+	RootOnlyFlags []string
+
+	// GlobalArgsValidator is applied to the args of whichever command is selected, in addition to its own
+	// ArgsValidator, and is wrapped the same way when it returns an error. Only read from the root command. Optional.
+	GlobalArgsValidator ArgsValidator
+
+	// OnValidationError is called in place of printing the usage when the args or flags fail validation, or Exec
+	// returns an ErrInvalidArguments, with the
+	// error that will be returned, for example to print a short hint to run with -h instead of the full usage.
+	// Subcommands without their own OnValidationError use the one of their nearest ancestor, as parsed. Optional.
+	OnValidationError func(c *Command, err error)
+
 	// Exec is the function that does the actual work, most Command's will implement this, unless they are just a
 	// namespace for Subcommands.
 	// The error returned by Exec will be bubble up and be returned by Run and ParseAndRun.
 	// If flag.ErrHelp or ErrInvalidArguments is returned the commands usage will be printed to the output.
-	Exec func(ctx context.Context, args []string) error
+	// If ErrShowUsage is returned the usage is printed and Run succeeds.
+	Exec ExecFunc
+
+	// ExecChain is run in place of Exec when Exec is not set, calling each function in order with the same context and
+	// args and stopping at the first error. Useful for composing steps, such as validating before doing. Optional.
+	ExecChain []ExecFunc
+
+	// PersistentPreRun is called by Run before the Exec of this command or any selected descendant, with the same
+	// context and args. The hooks of each command of the selected chain run in order from the root to the leaf, inside
+	// any Middleware, and an error stops the run. Useful for global setup such as initializing logging. Optional.
+	PersistentPreRun ExecFunc
+
+	// PersistentPostRun mirrors PersistentPreRun, running from the leaf to the root once Exec has succeeded. Optional.
+	PersistentPostRun ExecFunc
+
+	// OnNoExec is called by Run in place of returning a NoExecError when the command is selected but has no Exec,
+	// such as a namespace command invoked without a subcommand. A common implementation prints c.UsageString() and
+	// returns flag.ErrHelp. Optional.
+	OnNoExec func(c *Command) error
+
+	// ContextDecorator derives the context passed to the Exec of this command or any selected descendant, such as to
+	// add a logger or configuration shared by the whole tree. Run applies the ContextDecorator of each command of the
+	// selected chain once, from the root to the leaf, before calling OnRun. Optional.
+	ContextDecorator func(ctx context.Context) context.Context
+
+	// OnRun is called by Run for each command of the selected chain in order from the root to the leaf, before any
+	// Middleware or Exec. Useful for tracing, such as starting a span for each level of the command path. Optional.
+	OnRun func(ctx context.Context, c *Command)
+
+	// Middleware wraps Exec, the first middleware being the outermost. Middleware of a parent command also wraps the
+	// Exec of any selected descendant, outside the descendants own middleware. Optional.
+	Middleware []func(next ExecFunc) ExecFunc
+
+	// Messages overrides the text of the default usage output and of the errors built by Parse, for example to
+	// translate them. Subcommands without their own Messages use the ones of their nearest ancestor, as parsed.
+	// Optional.
+	Messages *Messages
+
+	// HelpOutput is where the usage is written when help is requested, either with -h or by Exec returning
+	// flag.ErrHelp or ErrShowUsage. Usage printed because of an error is still written to the output of FlagSet.
+	// Subcommands without their own HelpOutput inherit it from their parent. Optional, defaults to the output of
+	// FlagSet if it has been set to something other than os.Stderr, otherwise os.Stdout.
+	HelpOutput io.Writer
+
+	// Output is where Exec functions should write their output, accessed through OutputFromContext.
+	// Subcommands without their own Output inherit it from their parent. Optional, defaults to Stdout.
+	Output io.Writer
+
+	// RequireTTY makes Run return an ErrNotTerminal instead of calling Exec unless both Stdin and Stdout are
+	// terminals, for interactive commands such as prompts or editors that cannot work when piped. Optional.
+	RequireTTY bool
+
+	// SingleInstance makes Run hold an exclusive lock on LockFile while Exec runs, returning an ErrAlreadyRunning if
+	// another process holds it, for commands that must not run concurrently. The lock is released by the OS if the
+	// process exits on Unix and Windows. Elsewhere it is a file that is left behind by a process that exits without
+	// releasing it, such as on a crash, and must be removed by hand. Optional.
+	SingleInstance bool
+
+	// LockFile is the path of the file locked when SingleInstance is set. Optional, defaults to a file named after the
+	// command path in os.TempDir.
+	LockFile string
+
+	// Timeout limits how long Run lets Exec run by cancelling its context, returning an error that wraps ErrTimeout
+	// if Exec is still running when it expires. Subcommands with a Timeout of 0 inherit the nearest Timeout of their
+	// ancestors, and a negative Timeout disables it for a subcommand and its descendants. Optional.
+	Timeout time.Duration
+
+	// ErrorMapper rewrites the errors returned by Run once Exec and its hooks have run, for example to map known
+	// errors to friendlier messages. It is not called when Run succeeds. Subcommands without their own ErrorMapper
+	// inherit the nearest one of their ancestors. Optional.
+	ErrorMapper func(err error) error
+
+	// Stdin, Stdout and Stderr are the standard streams of Exec functions, accessed through StdinFromContext,
+	// StdoutFromContext and StderrFromContext, so commands can be tested with buffers. Subcommands without their own
+	// streams inherit them from their parent. Optional, default to os.Stdin, os.Stdout and os.Stderr.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Quiet discards everything written to OutputFromContext by this command and its subcommands. Optional.
+	Quiet bool
+
+	// BufferOutput buffers everything written to OutputFromContext by Exec, only writing it to Output if Exec
+	// succeeds, so that partial output is discarded on failure. Optional.
+	BufferOutput bool
+
+	// EchoCommand prints the resolved command line, including the values of any flags that were set, before the
+	// selected command is executed. Applies to all subcommands when set on a parent. Optional.
+	EchoCommand bool
+
+	// ShowInheritedFlags adds a section to the default usage of subcommands listing the flags of their ancestors,
+	// which are passed before the name of the subcommand. Applies to all subcommands when set on a parent. Ancestors
+	// are only known once the command has been parsed. Optional.
+	ShowInheritedFlags bool
+
+	// MaxDepth is the maximum depth of subcommands that Parse will descend into before returning an ErrMaxDepth.
+	// A command selected again below itself is reported as an ErrCommandCycle instead. Only read from the root
+	// command. Optional, defaults to 64.
+	MaxDepth int
+
+	// ErrorFormat selects how HandleError writes errors, defaults to ErrorFormatText. Optional.
+	ErrorFormat ErrorFormat
 
 	selected *Command // the command that was selected by parse
 
+	parent *Command // the command this was parsed as a subcommand of
+
+	parseCtx context.Context // the context passed to ParseContext, for calling Enabled
+
 	args []string // remaining args after flag parsing that should be passed to Exec function
+
+	rawArgs []string // args as they were passed to parse, before any transformation
+
+	unknownFlags []string // flags not defined in FlagSet, recorded when CaptureUnknownFlags is set
+
+	config map[string][]string // values of ConfigFile, read when the root command is parsed
 }
 
-// Name of the command is derived from first word of Usage
+// Name of the command is derived from first word of Usage, unless NameOverride is set
 func (c *Command) Name() string {
+	if c.NameOverride != "" {
+		return c.NameOverride
+	}
+
 	name := c.Usage
 	i := strings.Index(name, " ")
 	if i >= 0 {
@@ -70,47 +359,256 @@ func (c *Command) Name() string {
 
 // Parse the command line arguments for this command and all sub-commands
 func (c *Command) Parse(args []string) error {
+	return c.ParseContext(context.Background(), args)
+}
+
+// ParseContext is like Parse, passing ctx to the Enabled functions of subcommands.
+func (c *Command) ParseContext(ctx context.Context, args []string) error {
+	c.parseCtx = ctx
+	if err := c.checkSharedFlagSets(); err != nil {
+		return err
+	}
+
+	if c.EnableArgv0Dispatch && len(os.Args) > 0 {
+		name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+		if c.subcommand(name) != nil {
+			args = append([]string{name}, args...)
+		}
+	}
+	return c.parse(args, nil)
+}
+
+// parse implements Parse, where ancestors are the commands from the root down to the parent of c.
+func (c *Command) parse(args []string, ancestors []*Command) error {
+	path := append(ancestors[:len(ancestors):len(ancestors)], c)
+	for _, ancestor := range ancestors {
+		if ancestor == c {
+			return fmt.Errorf("%w: %s", ErrCommandCycle, commandPath(path))
+		}
+	}
+	if len(ancestors) > path[0].maxDepth() {
+		return fmt.Errorf("%w: %s", ErrMaxDepth, commandPath(path))
+	}
+
 	if c.selected != nil {
 		return nil
 	}
 
+	c.rawArgs = append([]string{}, args...)
+	c.parent = nil
+	if len(ancestors) > 0 {
+		c.parent = ancestors[len(ancestors)-1]
+	}
+
+	if c.ArgsTransform != nil {
+		args = c.ArgsTransform(args)
+	}
+
 	if c.FlagSet == nil {
-		c.FlagSet = flag.NewFlagSet(c.Name(), flag.ExitOnError)
+		c.FlagSet = flag.NewFlagSet(c.Name(), c.FlagErrorHandling)
+	} else if !c.PreserveFlagErrorHandling && c.FlagSet.ErrorHandling() != flag.ContinueOnError {
+		c.FlagSet.Init(c.FlagSet.Name(), flag.ContinueOnError)
 	}
 
+	if err := c.checkRootOnlyFlags(path); err != nil {
+		return err
+	}
+
+	if err := c.registerFlagAliases(); err != nil {
+		return err
+	}
+
+	// added before parsing so the usage printed for -h lists it
+	c.addVersionCommand()
+
 	if c.UsageFunc == nil {
 		c.UsageFunc = defaultUsageFunc
 	}
 
+	usage := func() {
+		c.printUsage(c.FlagSet.Output())
+	}
+
+	// usage requested with -h is written to the help output once parsing has failed with flag.ErrHelp, any other
+	// parse error writes it to the flag output
+	usageCalled := false
 	c.FlagSet.Usage = func() {
-		_, _ = fmt.Fprintln(c.FlagSet.Output(), c.UsageFunc(c))
+		usageCalled = true
+	}
+
+	// the args from the first subcommand on are left for the subcommand when StopAtSubcommand is set
+	var rest []string
+	if c.StopAtSubcommand {
+		for i, arg := range args {
+			if arg == "--" {
+				break
+			}
+			if c.subcommand(arg) != nil {
+				args, rest = args[:i], args[i:]
+				break
+			}
+		}
 	}
 
-	if err := c.FlagSet.Parse(args); err != nil {
+	// a subcommand of a StopAtSubcommand command receives its args untouched
+	passthrough := c.parent != nil && c.parent.StopAtSubcommand
+	if passthrough {
+		args, rest = []string{}, args
+	}
+
+	if c.CombinedShortFlags {
+		args = expandShortFlags(c.FlagSet, args)
+	}
+
+	if c.CaptureUnknownFlags {
+		args, c.unknownFlags = splitUnknownFlags(c.FlagSet, args)
+	}
+
+	// parsed with ContinueOnError so that usage is printed before an ExitOnError or PanicOnError FlagSet applies its
+	// own handling below
+	handling := c.FlagSet.ErrorHandling()
+	var err error
+	if c.DisableHelpFlag && c.FlagSet.Lookup("h") == nil && passesHelpShorthand(c.FlagSet, args) {
+		// rejected as the flag package rejects undefined flags, as it would otherwise be a request for help
+		err = errors.New("flag provided but not defined: -h")
+		_, _ = fmt.Fprintln(c.FlagSet.Output(), err)
+		usageCalled = true
+	} else {
+		c.FlagSet.Init(c.FlagSet.Name(), flag.ContinueOnError)
+		err = c.FlagSet.Parse(args)
+		c.FlagSet.Init(c.FlagSet.Name(), handling)
+	}
+	c.FlagSet.Usage = usage
+	switch {
+	case usageCalled && errors.Is(err, flag.ErrHelp):
+		c.printUsage(helpOutput(path))
+	case usageCalled:
+		usage()
+	}
+	if err != nil {
+		switch handling {
+		case flag.ExitOnError:
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			os.Exit(2)
+		case flag.PanicOnError:
+			panic(err)
+		}
+		if !errors.Is(err, flag.ErrHelp) {
+			err = flagParseError{err}
+		}
+		return err
+	}
+
+	failOnDeprecated := false
+	for _, cmd := range path {
+		failOnDeprecated = failOnDeprecated || cmd.FailOnDeprecated
+	}
+
+	var deprecated error
+	c.FlagSet.Visit(func(f *flag.Flag) {
+		name, ok := c.FlagAliases[f.Name]
+		switch {
+		case !ok:
+		case failOnDeprecated && deprecated == nil:
+			deprecated = fmt.Errorf("flag -%s is deprecated, use -%s instead", f.Name, name)
+		case !failOnDeprecated:
+			_, _ = fmt.Fprintf(c.FlagSet.Output(), "Flag -%s is deprecated, use -%s instead\n", f.Name, name)
+		}
+	})
+	if deprecated != nil {
+		return c.invalidArguments(deprecated)
+	}
+
+	if prefix := envPrefix(path); prefix != "" {
+		if err := c.setFromEnv(prefix); err != nil {
+			return err
+		}
+	}
+
+	if root := path[0]; root.ConfigFile != "" {
+		if c == root {
+			values, err := root.readConfig(root.ConfigFile)
+			if err != nil {
+				return err
+			}
+			root.config = values
+		}
+		if err := c.applyConfig(root, root.ConfigFile, root.config); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateFlags(); err != nil {
 		return err
 	}
 
 	c.args = c.FlagSet.Args()
+	flagArgs := args[:len(args)-len(c.args)]
+	if rest != nil {
+		c.args = append(c.args[:len(c.args):len(c.args)], rest...)
+	}
+
+	// args after a "--" separator are not expanded by AliasCommands or rejected as unknown subcommands, though one
+	// naming a subcommand still selects it
+	separated := len(flagArgs) > 0 && flagArgs[len(flagArgs)-1] == "--"
+
+	if len(c.args) > 0 && !separated && c.subcommand(c.args[0]) == nil {
+		if tokens, ok := c.AliasCommands[c.args[0]]; ok {
+			c.args = append(append([]string(nil), tokens...), c.args[1:]...)
+		}
+	}
+
 	if len(c.args) > 0 {
-		for _, cmd := range c.Subcommands {
-			if cmd.selectedBy(c.args[0]) {
-				c.selected = cmd
-				return cmd.Parse(c.args[1:])
-			}
+		if cmd := c.subcommand(c.args[0]); cmd != nil {
+			cmd.parseCtx = c.parseCtx
+			c.selected = cmd
+			return cmd.parse(c.args[1:], path)
+		}
+
+		if (c.UnknownSubcommandIsError || c.disabledSubcommand(c.args[0])) && len(c.Subcommands) > 0 && !separated {
+			return UnknownSubcommandError{Command: c, Name: c.args[0], Suggestions: c.suggestSubcommands(c.args[0])}
 		}
 	}
 
 	c.selected = c
 
-	if c.Exec == nil {
+	if c.exec() == nil {
+		if c.OnNoExec != nil {
+			return nil // handled when the command is run
+		}
 		c.FlagSet.Usage()
 		return NoExecError{Command: c}
 	}
 
-	if c.ArgsValidator != nil {
-		if err := c.ArgsValidator(c.args); err != nil {
-			c.FlagSet.Usage()
-			return fmt.Errorf("%w: %s", ErrInvalidArguments, err.Error())
+	if c.RequireArgSeparator && len(c.args) > 0 {
+		if !separated {
+			tokens := []string{commandPath(path)}
+			for _, arg := range flagArgs {
+				tokens = append(tokens, quoteToken(arg))
+			}
+			tokens = append(tokens, "--")
+			for _, arg := range c.args {
+				tokens = append(tokens, quoteToken(arg))
+			}
+			return c.invalidArguments(fmt.Errorf("positional args must follow a \"--\" separator, as in: %s", strings.Join(tokens, " ")))
+		}
+	}
+
+	if len(c.args) < len(c.ArgDefaults) {
+		c.args = append(append([]string{}, c.args...), c.ArgDefaults[len(c.args):]...)
+	}
+
+	if validator := c.argsValidator(); validator != nil {
+		if err := validator(c.args); err != nil {
+			return c.argsRejected(validator, err)
+		}
+	}
+
+	if global := path[0].GlobalArgsValidator; global != nil {
+		if err := global(c.args); err != nil {
+			return c.argsRejected(global, err)
 		}
 	}
 
@@ -118,49 +616,640 @@ func (c *Command) Parse(args []string) error {
 }
 
 // Run executes the previously selected command from a parsed Command.
-func (c *Command) Run(ctx context.Context) (err error) {
+func (c *Command) Run(ctx context.Context) error {
+	if c.selected == nil {
+		return ErrUnparsed
+	}
+
+	return runChain(ctx, c.selectedChain())
+}
+
+// ParseAndRun is a helper function to execute parse and run in a single invocation.
+func (c *Command) ParseAndRun(ctx context.Context, args []string) error {
+	if err := c.ParseContext(ctx, args); err != nil {
+		return err
+	}
+
+	if err := c.Run(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ParseAndValidate parses args like Parse, running the ArgsValidator and flag validators of the selected commands,
+// without running them. A NoExecError is returned if the selected command has neither an Exec nor an OnNoExec, as
+// Run would, so a command line can be checked before it is run, for example in CI.
+func (c *Command) ParseAndValidate(args []string) error {
+	if err := c.Parse(args); err != nil {
+		return err
+	}
+
+	chain := c.selectedChain()
+	if selected := chain[len(chain)-1]; selected.exec() == nil && selected.OnNoExec == nil {
+		return NoExecError{Command: selected}
+	}
+	return nil
+}
+
+// UsageString returns the usage of the command as it is printed, from UsageFunc or the default usage if UsageFunc is
+// not set. Useful for comparing help output against golden files in tests.
+func (c *Command) UsageString() string {
+	c.addVersionCommand()
+	if c.UsageFunc != nil {
+		return c.UsageFunc(c)
+	}
+	return defaultUsageFunc(c)
+}
+
+// Execute is an entrypoint for simple programs that parses and runs the command with a background context then exits.
+// Any error is written to os.Stderr with HandleError, except flag.ErrHelp, for which usage has already been printed,
+// and, with ErrorFormatText, errors parsing flags, which the FlagSet has already written along with the usage.
+// The program exits with 0 on success or help, 2 for invalid arguments or flags and 1 for any other error.
+func (c *Command) Execute(args []string) {
+	err := c.ParseAndRun(context.Background(), args)
+	reported := errors.Is(err, flag.ErrHelp) || errors.As(err, new(flagParseError)) && c.ErrorFormat == ErrorFormatText
+	if err != nil && !reported {
+		_ = c.HandleError(os.Stderr, err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// LookupCommand returns the descendant of c found by following path, where each element is the name or an alias of a
+// subcommand. An empty path returns c. An ErrUnknownCommand is returned if any element of path does not match.
+func (c *Command) LookupCommand(path ...string) (*Command, error) {
+	chain, err := c.lookupChain(path)
+	if err != nil {
+		return nil, err
+	}
+	return chain[len(chain)-1], nil
+}
+
+// LookupCommandPrefix is like LookupCommand, but each element of path may also be a unique prefix of the name or an
+// alias of a subcommand. An exact match is always preferred, and an ErrAmbiguousCommand is returned if an element is a
+// prefix of more than one subcommand.
+func (c *Command) LookupCommandPrefix(path ...string) (*Command, error) {
+	chain := []*Command{c}
+	for _, name := range path {
+		parent := chain[len(chain)-1]
+
+		sub := parent.subcommand(name)
+		if sub == nil {
+			var matches []*Command
+			for _, cmd := range parent.enabledSubcommands() {
+				for _, s := range cmd.names() {
+					s = strings.TrimPrefix(s, parent.SubcommandNamePrefix)
+					if len(s) >= len(name) && strings.EqualFold(s[:len(name)], name) {
+						matches = append(matches, cmd)
+						break
+					}
+				}
+			}
+
+			switch len(matches) {
+			case 0:
+				return nil, fmt.Errorf("%w: %q for %q", ErrUnknownCommand, name, commandPath(chain))
+			case 1:
+				sub = matches[0]
+			default:
+				names := make([]string, len(matches))
+				for i, cmd := range matches {
+					names[i] = cmd.Name()
+				}
+				return nil, fmt.Errorf("%w: %q for %q could be %s", ErrAmbiguousCommand, name, commandPath(chain), strings.Join(names, ", "))
+			}
+		}
+		chain = append(chain, sub)
+	}
+	return chain[len(chain)-1], nil
+}
+
+// RunSubcommand parses args for the descendant of c found by LookupCommand and runs it, without parsing the args of
+// any of the commands along path. Any previous parse of the descendant is discarded so it can be run repeatedly.
+func (c *Command) RunSubcommand(ctx context.Context, path []string, args []string) error {
+	chain, err := c.lookupChain(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkSharedFlagSets(); err != nil {
+		return err
+	}
+
+	target := chain[len(chain)-1]
+	target.reset()
+
+	if err := target.parse(args, chain[:len(chain)-1]); err != nil {
+		return err
+	}
+	return runChain(ctx, append(chain[:len(chain)-1], target.selectedChain()...))
+}
+
+// Parent returns the command that c was parsed as a subcommand of, or nil for the root or a command that has not been
+// parsed. Useful in middleware and hooks to walk up the tree, such as to look up a flag of an ancestor.
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// RawArgs returns a copy of the args passed to Parse for this command, before any ArgsTransform or flag parsing.
+// For a subcommand these are the args that followed its name. Returns nil if the command has not been parsed.
+func (c *Command) RawArgs() []string {
+	if c.rawArgs == nil {
+		return nil
+	}
+	return append([]string{}, c.rawArgs...)
+}
+
+// FlagChanged reports whether the flag called name was explicitly set on the command selected by Parse, rather than
+// being left at its default value.
+func (c *Command) FlagChanged(name string) bool {
+	chain := c.selectedChain()
+	fs := chain[len(chain)-1].FlagSet
+	if fs == nil {
+		return false
+	}
+
+	changed := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			changed = true
+		}
+	})
+	return changed
+}
+
+// UnknownFlags returns a copy of the flags that were not defined in FlagSet, as they were passed to Parse.
+// Only recorded when CaptureUnknownFlags is set.
+func (c *Command) UnknownFlags() []string {
+	return append([]string(nil), c.unknownFlags...)
+}
+
+// AddCommands appends subs to the Subcommands of c. If any name or alias of subs conflicts with an existing
+// subcommand or with another of subs, an error is returned and none of subs are added.
+func (c *Command) AddCommands(subs ...*Command) error {
+	cmds := make([]*Command, 0, len(c.Subcommands)+len(subs))
+	cmds = append(append(cmds, c.Subcommands...), subs...)
+
+	if err := checkDuplicates(cmds, c.SubcommandNamePrefix); err != nil {
+		return err
+	}
+
+	c.Subcommands = cmds
+	return nil
+}
+
+// validateFlags runs FlagValidators against the flags that were set.
+func (c *Command) validateFlags() error {
+	var err error
+	c.FlagSet.Visit(func(f *flag.Flag) {
+		if v, ok := c.FlagValidators[f.Name]; ok && err == nil {
+			if vErr := v(f.Value.String()); vErr != nil {
+				err = c.invalidArguments(fmt.Errorf("invalid value %q for flag -%s: %w", f.Value, f.Name, vErr))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+	c.FlagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		if name, ok := c.FlagAliases[f.Name]; ok {
+			set[name] = true
+		}
+	})
+
+	for _, group := range c.AtLeastOneFlag {
+		if len(group) == 0 {
+			continue
+		}
+
+		ok := false
+		for _, name := range group {
+			ok = ok || set[name]
+		}
+		if !ok {
+			return c.invalidArguments(fmt.Errorf("at least one of the flags -%s must be set", strings.Join(group, ", -")))
+		}
+	}
+	return nil
+}
+
+// registerFlagAliases registers each of FlagAliases as a flag sharing the value of the flag it refers to.
+func (c *Command) registerFlagAliases() error {
+	aliases := make([]string, 0, len(c.FlagAliases))
+	for alias := range c.FlagAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		if c.FlagSet.Lookup(alias) != nil {
+			continue
+		}
+
+		f := c.FlagSet.Lookup(c.FlagAliases[alias])
+		if f == nil {
+			return fmt.Errorf("flag alias -%s refers to undefined flag -%s", alias, c.FlagAliases[alias])
+		}
+		c.FlagSet.Var(f.Value, alias, f.Usage)
+	}
+	return nil
+}
+
+// checkSharedFlagSets records the command that owns each FlagSet in the tree rooted at c, returning an
+// ErrSharedFlagSet naming both commands if a second command has the same FlagSet, as values set while parsing one
+// would leak into the other.
+func (c *Command) checkSharedFlagSets() error {
+	owners := make(map[*flag.FlagSet][]*Command)
+	return c.Walk(func(path []*Command) error {
+		fs := path[len(path)-1].FlagSet
+		if fs == nil {
+			return nil
+		}
+		if owner, ok := owners[fs]; ok {
+			return fmt.Errorf("%w: %s and %s", ErrSharedFlagSet, commandPath(owner), commandPath(path))
+		}
+		owners[fs] = path
+		return nil
+	})
+}
+
+// maxDepth returns MaxDepth, or the default depth if it is not set.
+func (c *Command) maxDepth() int {
+	if c.MaxDepth > 0 {
+		return c.MaxDepth
+	}
+	return defaultMaxDepth
+}
+
+// Graft adds sub as a subcommand of the descendant of c found by LookupCommand with path, returning an error if the
+// path does not exist or sub conflicts with the name or alias of an existing subcommand there.
+func (c *Command) Graft(path []string, sub *Command) error {
+	parent, err := c.LookupCommand(path...)
+	if err != nil {
+		return err
+	}
+	return parent.AddCommands(sub)
+}
+
+// selectedChain returns the commands from c down to the command selected by Parse, stopping before any command that
+// is already in the chain so a cycle in the selections cannot loop forever.
+func (c *Command) selectedChain() []*Command {
+	chain := []*Command{c}
+	seen := map[*Command]bool{c: true}
+	for cmd := c; cmd.selected != nil && !seen[cmd.selected]; cmd = cmd.selected {
+		chain = append(chain, cmd.selected)
+		seen[cmd.selected] = true
+	}
+	return chain
+}
+
+// runChain executes the last command of chain, where chain runs from the command Run was called on down to the
+// selected command.
+func runChain(ctx context.Context, chain []*Command) (err error) {
+	c := chain[len(chain)-1]
 	if c.selected == nil {
 		return ErrUnparsed
 	}
 
-	if c.selected == c && c.Exec == nil {
+	if c.exec() == nil {
+		if c.OnNoExec != nil {
+			return c.OnNoExec(c)
+		}
 		return NoExecError{Command: c}
 	}
 
-	if c.selected == c && c.Exec != nil {
+	if mapper := chainErrorMapper(chain); mapper != nil {
+		defer func() {
+			if err != nil {
+				err = mapper(err)
+			}
+		}()
+	}
+
+	ctx = withStreams(ctx, chain)
+	if timeout := chainTimeout(chain); timeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		ctx = timeoutCtx
 		defer func() {
-			if errors.Is(err, flag.ErrHelp) || errors.Is(err, ErrInvalidArguments) {
-				c.FlagSet.Usage()
+			if err != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%s: %w after %s", commandPath(chain), ErrTimeout, timeout)
 			}
 		}()
+	}
+	if c.RequireTTY && (!isTerminal(StdinFromContext(ctx)) || !isTerminal(StdoutFromContext(ctx))) {
+		return fmt.Errorf("%w: %s", ErrNotTerminal, commandPath(chain))
+	}
+
+	ctx, printWarnings := withWarnings(ctx, StderrFromContext(ctx))
+	defer printWarnings()
+
+	if c.SingleInstance {
+		path := c.LockFile
+		if path == "" {
+			path = filepath.Join(os.TempDir(), strings.ReplaceAll(commandPath(chain), " ", "-")+".lock")
+		}
+
+		release, lockErr := acquireLock(path)
+		if lockErr != nil {
+			return fmt.Errorf("%s: %w", commandPath(chain), lockErr)
+		}
+		defer release()
+	}
+
+	defer func() {
+		switch {
+		case errors.Is(err, ErrShowUsage):
+			c.printUsage(helpOutput(chain))
+			err = nil
+		case errors.Is(err, flag.ErrHelp):
+			c.printUsage(helpOutput(chain))
+		case errors.Is(err, ErrInvalidArguments) && c.onValidationError() != nil:
+			c.onValidationError()(c, err)
+		case errors.Is(err, ErrInvalidArguments):
+			c.FlagSet.Usage()
+		}
+	}()
+
+	for _, cmd := range chain {
+		if cmd.EchoCommand {
+			out := c.FlagSet.Output()
+			if chain[0].FlagSet != nil {
+				out = chain[0].FlagSet.Output()
+			}
+			_, _ = fmt.Fprintf(out, "Running: %s\n", commandLine(chain))
+			break
+		}
+	}
+
+	for _, cmd := range chain {
+		if cmd.ContextDecorator != nil {
+			ctx = cmd.ContextDecorator(ctx)
+		}
+	}
+
+	for _, cmd := range chain {
+		if cmd.OnRun != nil {
+			cmd.OnRun(ctx, cmd)
+		}
+	}
 
-		return c.Exec(ctx, c.args)
+	exec := withPersistentRuns(c.exec(), chain)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for j := len(chain[i].Middleware) - 1; j >= 0; j-- {
+			exec = chain[i].Middleware[j](exec)
+		}
+	}
+
+	out := chainOutput(chain)
+	if !c.BufferOutput {
+		return exec(context.WithValue(ctx, outputKey, out), c.args)
 	}
 
-	if err = c.selected.Run(ctx); err != nil {
+	var buf bytes.Buffer
+	if err = exec(context.WithValue(ctx, outputKey, &buf), c.args); err != nil && !errors.Is(err, ErrShowUsage) {
 		return err
 	}
+	if _, wErr := buf.WriteTo(out); wErr != nil {
+		return wErr
+	}
+	return err
+}
+
+// printUsage writes the usage of c to w, ending it with a blank line unless PreserveUsageWhitespace is set.
+func (c *Command) printUsage(w io.Writer) {
+	if c.PreserveUsageWhitespace {
+		_, _ = fmt.Fprint(w, c.UsageString())
+		return
+	}
+	_, _ = fmt.Fprintln(w, c.UsageString())
+}
+
+// helpOutput returns where requested help for the last command of chain is written, see HelpOutput.
+func helpOutput(chain []*Command) io.Writer {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].HelpOutput != nil {
+			return chain[i].HelpOutput
+		}
+	}
+
+	if fs := chain[len(chain)-1].FlagSet; fs != nil && fs.Output() != os.Stderr {
+		return fs.Output()
+	}
+	return os.Stdout
+}
 
+// chainErrorMapper returns the ErrorMapper of the last command of chain, or of its nearest ancestor with one.
+func chainErrorMapper(chain []*Command) func(err error) error {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].ErrorMapper != nil {
+			return chain[i].ErrorMapper
+		}
+	}
 	return nil
 }
 
-// ParseAndRun is a helper function to execute parse and run in a single invocation.
-func (c *Command) ParseAndRun(ctx context.Context, args []string) error {
-	if err := c.Parse(args); err != nil {
-		return err
+// chainTimeout returns the Timeout of the last command of chain, or of its nearest ancestor with one.
+func chainTimeout(chain []*Command) time.Duration {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Timeout != 0 {
+			return chain[i].Timeout
+		}
 	}
+	return 0
+}
 
-	if err := c.Run(ctx); err != nil {
-		return err
+// withPersistentRuns wraps exec with the PersistentPreRun and PersistentPostRun hooks of chain.
+func withPersistentRuns(exec ExecFunc, chain []*Command) ExecFunc {
+	return func(ctx context.Context, args []string) error {
+		for _, cmd := range chain {
+			if cmd.PersistentPreRun != nil {
+				if err := cmd.PersistentPreRun(ctx, args); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := exec(ctx, args); err != nil {
+			return err
+		}
+
+		for i := len(chain) - 1; i >= 0; i-- {
+			if chain[i].PersistentPostRun != nil {
+				if err := chain[i].PersistentPostRun(ctx, args); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// exec returns Exec, or a function running each of ExecChain if Exec is not set, or nil if neither is set.
+func (c *Command) exec() ExecFunc {
+	if c.Exec != nil || len(c.ExecChain) == 0 {
+		return c.Exec
 	}
 
+	chain := c.ExecChain
+	return func(ctx context.Context, args []string) error {
+		for _, exec := range chain {
+			if err := exec(ctx, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// commandLine formats the invocation of the last command of chain, with the flags explicitly set on each command as
+// -name=value in name order, followed by the positional args. Tokens are quoted where needed.
+func commandLine(chain []*Command) string {
+	var tokens []string
+	for i, cmd := range chain {
+		if name := typedName(chain, i); name != "" {
+			tokens = append(tokens, quoteToken(name))
+		}
+		if cmd.FlagSet == nil {
+			continue // not parsed when run with RunSubcommand
+		}
+		cmd.FlagSet.Visit(func(f *flag.Flag) {
+			value := f.Value.String()
+			if r, ok := f.Value.(interface{ redacted() string }); ok {
+				value = r.redacted()
+			}
+			tokens = append(tokens, quoteToken(fmt.Sprintf("-%s=%s", f.Name, value)))
+		})
+	}
+
+	c := chain[len(chain)-1]
+	if len(c.args) > 0 {
+		tokens = append(tokens, "--")
+	}
+	for _, arg := range c.args {
+		tokens = append(tokens, quoteToken(arg))
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// commandPath joins the names of the commands in chain, as they would be typed on the command line.
+func commandPath(chain []*Command) string {
+	names := make([]string, len(chain))
+	for i := range chain {
+		names[i] = typedName(chain, i)
+	}
+	return strings.Join(names, " ")
+}
+
+// typedName returns the name of the command at index i of chain as it is typed on the command line, without the
+// SubcommandNamePrefix of its parent.
+func typedName(chain []*Command, i int) string {
+	if i == 0 {
+		return chain[i].Name()
+	}
+	return chain[i].typedNames(chain[i-1].SubcommandNamePrefix)[0]
+}
+
+// quoteToken single-quotes s if it would not be read back as a single token by a POSIX shell.
+func quoteToken(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"'\\$`|&;<>()*?[]#~") {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	return s
+}
+
+// lookupChain returns the commands from c down to the descendant found by following path.
+func (c *Command) lookupChain(path []string) ([]*Command, error) {
+	chain := []*Command{c}
+	for _, name := range path {
+		sub := chain[len(chain)-1].subcommand(name)
+		if sub == nil {
+			return nil, fmt.Errorf("%w: %q for %q", ErrUnknownCommand, name, commandPath(chain))
+		}
+		chain = append(chain, sub)
+	}
+	return chain, nil
+}
+
+// subcommand returns the enabled subcommand of c selected by name, or nil if there is none.
+func (c *Command) subcommand(name string) *Command {
+	for _, cmd := range c.Subcommands {
+		if cmd.selectedBy(name, c.SubcommandNamePrefix) && c.subcommandEnabled(cmd) {
+			return cmd
+		}
+	}
 	return nil
 }
 
-func (c *Command) selectedBy(name string) bool {
-	aliases := append([]string{c.Name()}, c.Aliases...)
+// disabledSubcommand reports whether name selects a subcommand of c that is not enabled.
+func (c *Command) disabledSubcommand(name string) bool {
+	for _, cmd := range c.Subcommands {
+		if cmd.selectedBy(name, c.SubcommandNamePrefix) && !c.subcommandEnabled(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// subcommandEnabled reports whether the Enabled function of sub, if any, allows it under the context c was parsed
+// with, or context.Background if c has not been parsed.
+func (c *Command) subcommandEnabled(sub *Command) bool {
+	if sub.Enabled == nil {
+		return true
+	}
+	ctx := c.parseCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return sub.Enabled(ctx)
+}
+
+// enabledSubcommands returns the Subcommands of c allowed by subcommandEnabled, in order.
+func (c *Command) enabledSubcommands() []*Command {
+	var subs []*Command
+	for _, sub := range c.Subcommands {
+		if c.subcommandEnabled(sub) {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// reset discards the selections made by a previous parse of c and its descendants.
+func (c *Command) reset() {
+	for cmd := c; cmd != nil; {
+		next := cmd.selected
+		cmd.selected = nil
+		if next == cmd {
+			break
+		}
+		cmd = next
+	}
+}
+
+// names returns the name of the command followed by its aliases.
+func (c *Command) names() []string {
+	return append([]string{c.Name()}, c.Aliases...)
+}
 
-	for _, s := range aliases {
+// typedNames returns the name and aliases of c as they are typed after a parent with the SubcommandNamePrefix
+// prefix, which is removed from each of them.
+func (c *Command) typedNames(prefix string) []string {
+	names := c.names()
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, prefix)
+	}
+	return names
+}
+
+// selectedBy reports whether name selects c, after removing prefix from the name and aliases of c.
+func (c *Command) selectedBy(name, prefix string) bool {
+	for _, s := range c.typedNames(prefix) {
 		if strings.EqualFold(name, s) {
 			return true
 		}
@@ -168,72 +1257,195 @@ func (c *Command) selectedBy(name string) bool {
 	return false
 }
 
+// nameConflicts describes each name or alias shared by two commands in cmds, compared as selectedBy does after
+// removing the SubcommandNamePrefix prefix of their parent.
+func nameConflicts(cmds []*Command, prefix string) []string {
+	var conflicts []string
+	seen := make(map[string]int)
+
+	for i, cmd := range cmds {
+		for _, name := range cmd.typedNames(prefix) {
+			key := strings.ToLower(name)
+			if j, ok := seen[key]; ok && j != i {
+				conflicts = append(conflicts, fmt.Sprintf("%q is used by both %s and %s", name, cmds[j].Name(), cmd.Name()))
+				continue
+			}
+			seen[key] = i
+		}
+	}
+	return conflicts
+}
+
+// checkDuplicates returns an error if any two commands in cmds share a name or alias under a parent with the
+// SubcommandNamePrefix prefix.
+func checkDuplicates(cmds []*Command, prefix string) error {
+	if conflicts := nameConflicts(cmds, prefix); len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateCommand, conflicts[0])
+	}
+	return nil
+}
+
 //goland:noinspection GoUnhandledErrorResult
 func defaultUsageFunc(c *Command) string {
 	var b strings.Builder
+	m := c.messages()
+	h := c.HelpSections()
 
-	fmt.Fprintln(&b, "USAGE")
-	if c.Usage != "" {
-		fmt.Fprintf(&b, " %s\n", c.Usage)
-	} else {
-		fmt.Fprintf(&b, " %s\n", c.Name())
-	}
+	fmt.Fprintln(&b, m.Usage)
+	fmt.Fprintf(&b, " %s\n", h.Usage)
 	fmt.Fprintln(&b)
 
-	if c.LongHelp != "" {
-		fmt.Fprintf(&b, "%s\n\n", c.LongHelp)
-	} else if c.ShortHelp != "" {
-		fmt.Fprintf(&b, "%s\n\n", c.ShortHelp)
+	if h.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", wrapText(h.Description, c.helpWidth()))
 	}
 
-	if len(c.Subcommands) > 0 {
-		fmt.Fprintln(&b, "SUBCOMMANDS")
+	if len(h.Subcommands) > 0 {
+		fmt.Fprintln(&b, m.Subcommands)
 		tw := tabwriter.NewWriter(&b, 0, 2, 2, ' ', 0)
 
-		for _, subcommand := range c.Subcommands {
-			fmt.Fprintf(tw, "  %s\t%s\n", subcommand.Name(), subcommand.ShortHelp)
+		nameWidth := 0
+		for _, sub := range h.Subcommands {
+			if n := utf8.RuneCountInString(sub.Name); n > nameWidth {
+				nameWidth = n
+			}
+		}
+
+		// the help column is truncated to the width left after the indent, names and padding
+		helpWidth := 0
+		if width := c.helpWidth(); width > 0 {
+			helpWidth = width - nameWidth - 4
+			if helpWidth < 1 {
+				helpWidth = 1
+			}
+		}
+
+		for _, sub := range h.Subcommands {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, truncateText(sub.ShortHelp, helpWidth))
 		}
 		tw.Flush()
 		fmt.Fprintln(&b)
 	}
 
 	if countFlags(c.FlagSet) > 0 {
-		fmt.Fprintln(&b, "FLAGS")
-
-		tw := tabwriter.NewWriter(&b, 0, 2, 2, ' ', 0)
-		c.FlagSet.VisitAll(func(f *flag.Flag) {
-			space := " "
-			if isBoolFlag(f) {
-				space = "="
-			}
-
-			def := f.DefValue
-			if def == "" {
-				def = "..."
-			}
-
-			fmt.Fprintf(tw, "  -%s%s%s\t%s\n", f.Name, space, def, f.Usage)
-		})
+		fmt.Fprintln(&b, m.Flags)
+		writeHelpFlags(&b, h.Flags, c.HideEmptyDefaults)
+		fmt.Fprintln(&b)
+	}
 
-		fmt.Fprintf(tw, "  -%s%s%s\t%s\n", "h", "=", "false", "prints help and usage for this command or subcommand")
+	if len(h.InheritedFlags) > 0 {
+		fmt.Fprintln(&b, m.InheritedFlags)
+		writeHelpFlags(&b, h.InheritedFlags, c.HideEmptyDefaults)
+		fmt.Fprintln(&b)
+	}
 
-		tw.Flush()
+	if len(h.Examples) > 0 {
+		fmt.Fprintln(&b, m.Examples)
+		for _, example := range h.Examples {
+			fmt.Fprintf(&b, "  %s\n", example)
+		}
 		fmt.Fprintln(&b)
 	}
 
+	if c.PreserveUsageWhitespace {
+		return b.String()
+	}
 	return strings.TrimSpace(b.String()) + "\n"
 }
 
+// writeHelpFlags writes a line for each flag to w, aligning their usage.
+//
+//goland:noinspection GoUnhandledErrorResult
+func writeHelpFlags(w io.Writer, flags []HelpFlag, hideEmptyDefaults bool) {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	for _, f := range flags {
+		// bool flags are switches, so only a default other than false is shown
+		if f.IsBool {
+			if f.Default == "" || f.Default == "false" {
+				fmt.Fprintf(tw, "  -%s\t%s\n", f.Name, f.Usage)
+			} else {
+				fmt.Fprintf(tw, "  -%s (default %s)\t%s\n", f.Name, f.Default, f.Usage)
+			}
+			continue
+		}
+
+		def := f.Default
+		if def == "" && hideEmptyDefaults {
+			fmt.Fprintf(tw, "  -%s\t%s\n", f.Name, f.Usage)
+			continue
+		}
+		if def == "" {
+			def = f.Type
+		}
+
+		fmt.Fprintf(tw, "  -%s %s\t%s\n", f.Name, def, f.Usage)
+	}
+	tw.Flush()
+}
+
 func countFlags(fs *flag.FlagSet) (n int) {
+	if fs == nil {
+		return 0
+	}
 	fs.VisitAll(func(f *flag.Flag) {
 		n++
 	})
 	return n
 }
 
+// flagType returns a name for the type of value f accepts. A Type method on the flag.Value is preferred, followed by a
+// name in backquotes in the usage of f, the type of the builtin flags, and finally the kind of the flag.Value.
+func flagType(f *flag.Flag) string {
+	if t, ok := f.Value.(interface{ Type() string }); ok {
+		return t.Type()
+	}
+
+	if name, _ := flag.UnquoteUsage(f); name != "value" {
+		return name
+	}
+
+	t := reflect.TypeOf(f.Value)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() <= reflect.Complex128 || t.Kind() == reflect.String {
+		return t.Kind().String()
+	}
+	return "value"
+}
+
+// flagUsage returns the usage of f without the backquotes that name the type of its value for flagType.
+func flagUsage(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	return usage
+}
+
 func isBoolFlag(f *flag.Flag) bool {
 	b, ok := f.Value.(interface {
 		IsBoolFlag() bool
 	})
 	return ok && b.IsBoolFlag()
 }
+
+// passesHelpShorthand reports whether -h is among the leading flags of args, before the first positional arg or "--",
+// reading the flags as fs would.
+func passesHelpShorthand(fs *flag.FlagSet, args []string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			return false
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if name == "h" {
+			return true
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			return false // rejected when fs parses args
+		}
+		if !hasValue && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return false
+}
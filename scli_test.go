@@ -5,8 +5,15 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCommand_ParseAndRun(t *testing.T) {
@@ -18,17 +25,27 @@ func TestCommand_ParseAndRun(t *testing.T) {
 		_          = rootFlags.Int64("int", 0, "int flag")
 		subFlags   = flag.NewFlagSet("subFlags", flag.ContinueOnError)
 		_          = subFlags.String("sub", "", "this is a flag in sub command")
+		hostFlags  = flag.NewFlagSet("hostFlags", flag.ContinueOnError)
+		_          = hostFlags.String("h", "", "host")
+		noHelp     = flag.NewFlagSet("noHelp", flag.ContinueOnError)
+		sharedFlag = flag.NewFlagSet("shared", flag.ContinueOnError)
 	)
 
 	tests := []struct {
-		Name          string
-		Usage         string
-		Subcommands   []*Command
-		FlagSet       *flag.FlagSet
-		ArgsValidator ArgsValidator
-		Exec          func(ctx context.Context, args []string) error
-		PassedArgs    []string
-		ErrCheck      func(error) bool
+		Name           string
+		Usage          string
+		Subcommands    []*Command
+		FlagSet        *flag.FlagSet
+		ArgsValidator  ArgsValidator
+		ArgsTransform  func(args []string) []string
+		NoHelpFlag     bool
+		RequireSep     bool
+		SubPrefix      string
+		FlagValidators map[string]func(string) error
+		ArgDefaults    []string
+		Exec           func(ctx context.Context, args []string) error
+		PassedArgs     []string
+		ErrCheck       func(error) bool
 	}{
 		{
 			Name:          "Root Help Flag",
@@ -38,6 +55,25 @@ func TestCommand_ParseAndRun(t *testing.T) {
 			PassedArgs:    []string{"-h"},
 			ErrCheck:      errorIs(flag.ErrHelp),
 		},
+		{
+			Name:          "Disabled Help Flag",
+			ArgsValidator: NoArgs(),
+			FlagSet:       hostFlags,
+			NoHelpFlag:    true,
+			Exec:          expectedFlags(hostFlags, fPair{"h", "example.com"}),
+			PassedArgs:    []string{"-h", "example.com"},
+		},
+		{
+			Name:          "Disabled Help Flag Undefined",
+			ArgsValidator: NoArgs(),
+			FlagSet:       noHelp,
+			NoHelpFlag:    true,
+			Exec:          returnsNil,
+			PassedArgs:    []string{"-h"},
+			ErrCheck: func(err error) bool {
+				return err != nil && err.Error() == "flag provided but not defined: -h"
+			},
+		},
 		{
 			Name:          "Invalid Args",
 			ArgsValidator: ExactArgs(1),
@@ -46,6 +82,17 @@ func TestCommand_ParseAndRun(t *testing.T) {
 			PassedArgs:    []string{},
 			ErrCheck:      errorIs(ErrInvalidArguments),
 		},
+		{
+			Name:          "Invalid Args Unwraps",
+			ArgsValidator: AnyValidator(NoArgs(), MinArgs(2)),
+			FlagSet:       emptyFlags,
+			Exec:          returnsNil,
+			PassedArgs:    []string{"a"},
+			ErrCheck: func(err error) bool {
+				return errorAs[InvalidArgumentsError]()(err) && errorAs[ValidatorChainError]()(err) &&
+					errors.Is(err, ErrInvalidArguments)
+			},
+		},
 		{
 			Name:          "Exec Invalid Args",
 			ArgsValidator: NoArgs(),
@@ -73,6 +120,28 @@ func TestCommand_ParseAndRun(t *testing.T) {
 			PassedArgs: []string{},
 			ErrCheck:   errorIs(flag.ErrHelp),
 		},
+		{
+			Name:          "Show Usage",
+			ArgsValidator: NoArgs(),
+			FlagSet:       emptyFlags,
+			Exec: func(ctx context.Context, args []string) error {
+				return ErrShowUsage
+			},
+			PassedArgs: []string{},
+		},
+		{
+			Name:          "Flag Validator",
+			ArgsValidator: NoArgs(),
+			FlagSet:       rootFlags,
+			FlagValidators: map[string]func(string) error{
+				"string": func(value string) error {
+					return OnlyValidArgs([]string{"bar"})([]string{value})
+				},
+			},
+			Exec:       returnsNil,
+			PassedArgs: []string{"-string", "baz"},
+			ErrCheck:   errorIs(ErrInvalidArguments),
+		},
 		{
 			Name:          "Root",
 			ArgsValidator: NoArgs(),
@@ -114,6 +183,46 @@ func TestCommand_ParseAndRun(t *testing.T) {
 			),
 			PassedArgs: []string{"-string", "bar", "-bool", "-int", "42", "--", "42", "foo", "bar"},
 		},
+		{
+			Name:          "Args Transform",
+			ArgsValidator: NoArgs(),
+			FlagSet:       rootFlags,
+			ArgsTransform: func(args []string) []string {
+				for i, arg := range args {
+					if arg == "-old" {
+						args[i] = "-string"
+					}
+				}
+				return args
+			},
+			Exec:       expectedFlags(rootFlags, fPair{"string", "renamed"}),
+			PassedArgs: []string{"-old", "renamed"},
+		},
+		{
+			Name:          "Require Separator",
+			ArgsValidator: ExactArgs(1),
+			FlagSet:       rootFlags,
+			RequireSep:    true,
+			Exec:          expectsArgs("-foo"),
+			PassedArgs:    []string{"-string", "bar", "--", "-foo"},
+		},
+		{
+			Name:          "Require Separator Missing",
+			ArgsValidator: ExactArgs(1),
+			FlagSet:       rootFlags,
+			RequireSep:    true,
+			Exec:          returnsNil,
+			PassedArgs:    []string{"-string", "bar", "foo"},
+			ErrCheck:      errorIs(ErrInvalidArguments),
+		},
+		{
+			Name:          "Arg Defaults",
+			ArgsValidator: ExactArgs(3),
+			FlagSet:       emptyFlags,
+			ArgDefaults:   []string{"a", "b", "c"},
+			Exec:          expectsArgs("x", "b", "c"),
+			PassedArgs:    []string{"x"},
+		},
 		{
 			Name:          "Root Sub",
 			ArgsValidator: NoArgs(),
@@ -124,7 +233,6 @@ func TestCommand_ParseAndRun(t *testing.T) {
 					ShortHelp:     "short help",
 					LongHelp:      "long help",
 					ArgsValidator: NoArgs(),
-					FlagSet:       emptyFlags,
 					Exec:          returnsNil,
 				},
 			},
@@ -142,12 +250,51 @@ func TestCommand_ParseAndRun(t *testing.T) {
 					ShortHelp:     "short help",
 					LongHelp:      "long help",
 					ArgsValidator: NoArgs(),
-					FlagSet:       emptyFlags,
 					Exec:          returnsNil,
 				},
 			},
 			PassedArgs: []string{"foobar"},
 		},
+		{
+			Name:          "Prefixed Sub",
+			Usage:         "mytool",
+			ArgsValidator: NoArgs(),
+			FlagSet:       emptyFlags,
+			Subcommands: []*Command{
+				{
+					Usage:         "mytool-foo",
+					ArgsValidator: NoArgs(),
+					Exec:          returnsNil,
+				},
+			},
+			SubPrefix:  "mytool-",
+			PassedArgs: []string{"foo"},
+		},
+		{
+			Name:          "Shared FlagSet",
+			ArgsValidator: NoArgs(),
+			FlagSet:       emptyFlags,
+			Subcommands: []*Command{
+				{
+					Usage:   "sub",
+					FlagSet: emptyFlags,
+					Exec:    returnsNil,
+				},
+			},
+			PassedArgs: []string{"sub"},
+			ErrCheck:   errorIs(ErrSharedFlagSet),
+		},
+		{
+			Name:          "Sibling Shared FlagSet",
+			ArgsValidator: NoArgs(),
+			FlagSet:       emptyFlags,
+			Subcommands: []*Command{
+				{Usage: "a", FlagSet: sharedFlag, Exec: returnsNil},
+				{Usage: "b", FlagSet: sharedFlag, Exec: returnsNil},
+			},
+			PassedArgs: []string{"b"},
+			ErrCheck:   errorIs(ErrSharedFlagSet),
+		},
 		{
 			Name:          "Root Flags Sub Flags",
 			ArgsValidator: NoArgs(),
@@ -210,13 +357,19 @@ func TestCommand_ParseAndRun(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
 			cmd := Command{
-				Usage:         tt.Usage,
-				ShortHelp:     fmt.Sprintf("%s short help string", tt.Name),
-				LongHelp:      fmt.Sprintf("%s long help string", tt.Name),
-				Subcommands:   tt.Subcommands,
-				FlagSet:       tt.FlagSet,
-				ArgsValidator: tt.ArgsValidator,
-				Exec:          tt.Exec,
+				Usage:                tt.Usage,
+				ShortHelp:            fmt.Sprintf("%s short help string", tt.Name),
+				LongHelp:             fmt.Sprintf("%s long help string", tt.Name),
+				Subcommands:          tt.Subcommands,
+				FlagSet:              tt.FlagSet,
+				ArgsValidator:        tt.ArgsValidator,
+				ArgsTransform:        tt.ArgsTransform,
+				DisableHelpFlag:      tt.NoHelpFlag,
+				RequireArgSeparator:  tt.RequireSep,
+				SubcommandNamePrefix: tt.SubPrefix,
+				FlagValidators:       tt.FlagValidators,
+				ArgDefaults:          tt.ArgDefaults,
+				Exec:                 tt.Exec,
 			}
 
 			if err := cmd.ParseAndRun(context.Background(), tt.PassedArgs); checkError(err, tt.ErrCheck) {
@@ -226,56 +379,1990 @@ func TestCommand_ParseAndRun(t *testing.T) {
 	}
 }
 
-func returnsNil(_ context.Context, _ []string) error {
-	return nil
+func TestCommand_AddCommands(t *testing.T) {
+	root := Command{Usage: "root"}
+
+	if err := root.AddCommands(&Command{Usage: "foo"}, &Command{Usage: "bar", Aliases: []string{"b"}}); err != nil {
+		t.Fatalf("AddCommands() error %v", err)
+	}
+
+	if err := root.AddCommands(&Command{Usage: "baz", Aliases: []string{"B"}}); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("AddCommands() error = %v, want %v", err, ErrDuplicateCommand)
+	}
+
+	if err := root.AddCommands(&Command{Usage: "qux"}, &Command{Usage: "qux"}); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("AddCommands() error = %v, want %v", err, ErrDuplicateCommand)
+	}
+
+	if len(root.Subcommands) != 2 {
+		t.Errorf("len(Subcommands) = %d, want 2", len(root.Subcommands))
+	}
+
+	prefixed := Command{Usage: "root", SubcommandNamePrefix: "my-tool-", Subcommands: []*Command{{Usage: "my-tool-foo"}}}
+	if err := prefixed.AddCommands(&Command{Usage: "foo"}); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("AddCommands() error = %v, want %v", err, ErrDuplicateCommand)
+	}
 }
 
-func combineExecs(execs ...func(ctx context.Context, args []string) error) func(ctx context.Context, args []string) error {
-	return func(ctx context.Context, args []string) error {
-		for _, f := range execs {
-			if err := f(ctx, args); err != nil {
-				return err
+func TestCommand_HandleError(t *testing.T) {
+	tests := []struct {
+		Name        string
+		ErrorFormat ErrorFormat
+		Err         error
+		Want        string
+	}{
+		{Name: "Nil", ErrorFormat: ErrorFormatJSON, Err: nil, Want: ""},
+		{Name: "Text", ErrorFormat: ErrorFormatText, Err: errors.New("boom"), Want: "boom\n"},
+		{Name: "JSON", ErrorFormat: ErrorFormatJSON, Err: errors.New("boom"), Want: `{"error":"boom","kind":"error"}` + "\n"},
+		{
+			Name:        "JSON Invalid Arguments",
+			ErrorFormat: ErrorFormatJSON,
+			Err:         fmt.Errorf("%w: bad", ErrInvalidArguments),
+			Want:        `{"error":"invalid arguments: bad","kind":"invalid_arguments"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b strings.Builder
+			cmd := Command{ErrorFormat: tt.ErrorFormat}
+
+			if err := cmd.HandleError(&b, tt.Err); err != tt.Err {
+				t.Errorf("HandleError() error = %v, want %v", err, tt.Err)
+			}
+			if b.String() != tt.Want {
+				t.Errorf("HandleError() wrote %q, want %q", b.String(), tt.Want)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		Name string
+		Err  error
+		Want int
+	}{
+		{Name: "Nil", Err: nil, Want: 0},
+		{Name: "Help", Err: fmt.Errorf("wrapped: %w", flag.ErrHelp), Want: 0},
+		{Name: "Invalid Arguments", Err: InvalidArgumentsError{Err: errors.New("bad")}, Want: 2},
+		{Name: "Flag Parse", Err: flagParseError{errors.New("flag provided but not defined: -bogus")}, Want: 2},
+		{Name: "Other", Err: errors.New("boom"), Want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if got := exitCode(tt.Err); got != tt.Want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.Want)
 			}
+		})
+	}
+}
+
+func TestCommand_Execute(t *testing.T) {
+	if args := os.Getenv("SCLI_TEST_EXECUTE"); args != "" {
+		cmd := Command{
+			Usage:         "root",
+			ArgsValidator: MaxArgs(1),
+			Exec: func(ctx context.Context, args []string) error {
+				if len(args) > 0 && args[0] == "fail" {
+					return errors.New("boom")
+				}
+				return nil
+			},
 		}
-		return nil
+		cmd.Execute(strings.Fields(args))
+		return
+	}
+
+	tests := []struct {
+		Name       string
+		Args       string
+		WantCode   int
+		WantStderr string
+	}{
+		{Name: "Success", Args: "ok", WantCode: 0},
+		{Name: "Help", Args: "-h", WantCode: 0},
+		{Name: "Invalid Arguments", Args: "a b", WantCode: 2, WantStderr: "invalid arguments"},
+		{Name: "Error", Args: "fail", WantCode: 1, WantStderr: "boom\n"},
+		{Name: "Undefined Flag", Args: "-bogus", WantCode: 2, WantStderr: "flag provided but not defined: -bogus\nUSAGE\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var stderr strings.Builder
+			cmd := exec.Command(os.Args[0], "-test.run=^TestCommand_Execute$")
+			cmd.Env = append(os.Environ(), "SCLI_TEST_EXECUTE="+tt.Args)
+			cmd.Stderr = &stderr
+
+			err := cmd.Run()
+			var exitErr *exec.ExitError
+			switch {
+			case tt.WantCode == 0 && err != nil:
+				t.Fatalf("Execute() exited with %v, want 0", err)
+			case tt.WantCode != 0 && !errors.As(err, &exitErr):
+				t.Fatalf("Execute() exited with %v, want %d", err, tt.WantCode)
+			case tt.WantCode != 0 && exitErr.ExitCode() != tt.WantCode:
+				t.Errorf("Execute() exit code = %d, want %d", exitErr.ExitCode(), tt.WantCode)
+			}
+			if !strings.Contains(stderr.String(), tt.WantStderr) {
+				t.Errorf("Execute() stderr = %q, want it to contain %q", stderr.String(), tt.WantStderr)
+			}
+			if line, _, _ := strings.Cut(tt.WantStderr, "\n"); line != "" && strings.Count(stderr.String(), line) != 1 {
+				t.Errorf("Execute() stderr = %q, want %q once", stderr.String(), line)
+			}
+		})
 	}
 }
 
-type fPair struct {
-	Name  string
-	Value any
+func TestCommand_Messages(t *testing.T) {
+	var b strings.Builder
+	subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+	subFlags.SetOutput(&b)
+
+	cmd := Command{
+		Usage:    "root",
+		Messages: &Messages{Usage: "UTILISATION", InvalidArguments: "arguments invalides : %s"},
+		Subcommands: []*Command{
+			{Usage: "sub", FlagSet: subFlags, ArgsValidator: ExactArgs(1), Exec: returnsNil},
+		},
+	}
+
+	err := cmd.ParseAndRun(context.Background(), []string{"sub"})
+	if !errors.Is(err, ErrInvalidArguments) {
+		t.Fatalf("ParseAndRun() error = %v, want %v", err, ErrInvalidArguments)
+	}
+
+	if want := "arguments invalides : requires exactly 1 arg(s), received 0"; err.Error() != want {
+		t.Errorf("ParseAndRun() error = %q, want %q", err.Error(), want)
+	}
+
+	if !strings.HasPrefix(b.String(), "UTILISATION\n sub\n") {
+		t.Errorf("usage = %q, want translated header", b.String())
+	}
+	if cmd.Subcommands[0].Messages != nil {
+		t.Error("ParseAndRun() set Messages of the subcommand")
+	}
+
+	cmd.Messages = &Messages{InvalidArguments: "ungültige Argumente: %s"}
+	cmd.reset()
+	err = cmd.ParseAndRun(context.Background(), []string{"sub"})
+	if want := "ungültige Argumente: requires exactly 1 arg(s), received 0"; err == nil || err.Error() != want {
+		t.Errorf("ParseAndRun() error = %v, want %q", err, want)
+	}
 }
 
-func expectedFlags(flagSet *flag.FlagSet, flagPairs ...fPair) func(ctx context.Context, args []string) error {
-	return func(ctx context.Context, args []string) error {
-		fvps := make(map[string]flag.Value)
+func TestCommand_UnknownSubcommandHint(t *testing.T) {
+	tests := []struct {
+		Name          string
+		ArgsValidator ArgsValidator
+		Args          []string
+		Want          string
+	}{
+		{
+			Name:          "Stray Arg",
+			ArgsValidator: NoArgs(),
+			Args:          []string{"sbu"},
+			Want:          `invalid arguments: expected no arguments, received 1: "sbu" ("sbu" may be a misspelled or missing subcommand of root)`,
+		},
+		{
+			Name:          "Missing Arg",
+			ArgsValidator: ExactArgs(2),
+			Args:          []string{"a"},
+			Want:          "invalid arguments: requires exactly 2 arg(s), received 1",
+		},
+	}
 
-		flagSet.VisitAll(func(f *flag.Flag) {
-			fvps[f.Name] = f.Value
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+			rootFlags.SetOutput(io.Discard)
+
+			cmd := Command{
+				Usage:         "root",
+				FlagSet:       rootFlags,
+				ArgsValidator: tt.ArgsValidator,
+				Exec:          returnsNil,
+				Subcommands:   []*Command{{Usage: "sub", Exec: returnsNil}},
+			}
+
+			err := cmd.Parse(tt.Args)
+			if !errors.Is(err, ErrInvalidArguments) {
+				t.Fatalf("Parse() error = %v, want %v", err, ErrInvalidArguments)
+			}
+			if err.Error() != tt.Want {
+				t.Errorf("Parse() error = %q, want %q", err.Error(), tt.Want)
+			}
 		})
+	}
+}
 
-		for _, fp := range flagPairs {
-			fv, ok := fvps[fp.Name]
+func TestCommand_EchoCommand(t *testing.T) {
+	var b strings.Builder
+	rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+	rootFlags.SetOutput(&b)
+	_ = rootFlags.Bool("v", false, "verbose")
+	subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = subFlags.String("name", "", "name")
 
-			if !ok {
-				return fmt.Errorf("missing flag for %s", fp.Name)
+	cmd := Command{
+		Usage:       "root",
+		FlagSet:     rootFlags,
+		EchoCommand: true,
+		Subcommands: []*Command{
+			{Usage: "sub", FlagSet: subFlags, Exec: returnsNil},
+		},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"-v", "sub", "-name", "it's $HOME", "x"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := `Running: root -v=true sub '-name=it'\''s $HOME' -- x` + "\n"; b.String() != want {
+		t.Errorf("echo = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCommand_EchoCommandPrefixed(t *testing.T) {
+	var b strings.Builder
+	rootFlags := flag.NewFlagSet("mytool", flag.ContinueOnError)
+	rootFlags.SetOutput(&b)
+
+	cmd := Command{
+		Usage:                "mytool",
+		FlagSet:              rootFlags,
+		EchoCommand:          true,
+		SubcommandNamePrefix: "mytool-",
+		Subcommands:          []*Command{{Usage: "mytool-foo", Exec: returnsNil}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"foo", "x y"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := "Running: mytool foo -- 'x y'\n"; b.String() != want {
+		t.Errorf("echo = %q, want %q", b.String(), want)
+	}
+}
+
+func TestOutputFromContext(t *testing.T) {
+	writeHello := func(ctx context.Context, args []string) error {
+		_, err := fmt.Fprint(OutputFromContext(ctx), "hello")
+		return err
+	}
+
+	tests := []struct {
+		Name  string
+		Quiet bool
+		Want  string
+	}{
+		{Name: "Inherited Output", Want: "hello"},
+		{Name: "Quiet", Quiet: true, Want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b strings.Builder
+			cmd := Command{
+				Usage:  "root",
+				Output: &b,
+				Quiet:  tt.Quiet,
+				Subcommands: []*Command{
+					{Usage: "sub", FlagSet: flag.NewFlagSet("sub", flag.ContinueOnError), Exec: writeHello},
+				},
 			}
 
-			s := fmt.Sprintf("%v", fp.Value)
-			if s != fv.String() {
-				return fmt.Errorf("invalid flag value for %s", fp.Name)
+			if err := cmd.ParseAndRun(context.Background(), []string{"sub"}); err != nil {
+				t.Fatalf("ParseAndRun() error %v", err)
 			}
-		}
-		return nil
+			if b.String() != tt.Want {
+				t.Errorf("output = %q, want %q", b.String(), tt.Want)
+			}
+		})
 	}
 }
 
-func expectsArgs(wantedArgs ...string) func(ctx context.Context, args []string) error {
-	return func(ctx context.Context, args []string) error {
-		if !reflect.DeepEqual(wantedArgs, args) {
-			return fmt.Errorf("expected args = %v, got = %v", wantedArgs, args)
+func TestStreamsFromContext(t *testing.T) {
+	var stdout, stderr, subStderr strings.Builder
+	cmd := Command{
+		Usage:  "root",
+		Stdin:  strings.NewReader("input"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Subcommands: []*Command{{
+			Usage:  "sub",
+			Stderr: &subStderr,
+			Exec: func(ctx context.Context, args []string) error {
+				in, err := io.ReadAll(StdinFromContext(ctx))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(StdoutFromContext(ctx), "%s to stdout", in)
+				fmt.Fprint(StderrFromContext(ctx), "to stderr")
+				fmt.Fprint(OutputFromContext(ctx), ", output")
+				return nil
+			},
+		}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"sub"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := "input to stdout, output"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if stderr.Len() != 0 || subStderr.String() != "to stderr" {
+		t.Errorf("stderr = %q, sub stderr = %q, want %q", stderr.String(), subStderr.String(), "to stderr")
+	}
+}
+
+func TestAddWarning(t *testing.T) {
+	var stderr strings.Builder
+	var got []string
+
+	cmd := Command{
+		Usage:  "root",
+		Stderr: &stderr,
+		Exec: func(ctx context.Context, args []string) error {
+			AddWarning(ctx, "skipped a")
+			AddWarning(ctx, "skipped b")
+			got = Warnings(ctx)
+			if stderr.Len() != 0 {
+				return errors.New("warnings printed before Exec returned")
+			}
+			return errors.New("failed")
+		},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), nil); err == nil || err.Error() != "failed" {
+		t.Fatalf("ParseAndRun() error = %v, want failed", err)
+	}
+
+	if want := []string{"skipped a", "skipped b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Warnings() = %q, want %q", got, want)
+	}
+	if want := "Warning: skipped a\nWarning: skipped b\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestCommand_RequireTTY(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	cmd := Command{
+		Usage:      "root",
+		Stdin:      strings.NewReader(""),
+		Stdout:     file,
+		RequireTTY: true,
+		Exec:       returnsNil,
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), nil); !errors.Is(err, ErrNotTerminal) {
+		t.Errorf("ParseAndRun() error = %v, want %v", err, ErrNotTerminal)
+	}
+}
+
+func TestCommand_SingleInstance(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "root.lock")
+
+	var nested error
+	cmd := Command{
+		Usage:          "root",
+		SingleInstance: true,
+		LockFile:       lockFile,
+		Exec: func(ctx context.Context, args []string) error {
+			other := Command{Usage: "root", SingleInstance: true, LockFile: lockFile, Exec: returnsNil}
+			nested = other.ParseAndRun(ctx, nil)
+			return nil
+		},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), nil); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+	if !errors.Is(nested, ErrAlreadyRunning) {
+		t.Errorf("nested ParseAndRun() error = %v, want %v", nested, ErrAlreadyRunning)
+	}
+
+	cmd.reset()
+	if err := cmd.ParseAndRun(context.Background(), nil); err != nil {
+		t.Errorf("ParseAndRun() after release error %v", err)
+	}
+}
+
+func TestCommand_UnknownSubcommandIsError(t *testing.T) {
+	newCommand := func() *Command {
+		return &Command{
+			Usage:                    "bar",
+			UnknownSubcommandIsError: true,
+			Subcommands: []*Command{
+				{Usage: "status", Exec: returnsNil},
+				{Usage: "stash", Exec: returnsNil},
+				{Usage: "commit", Aliases: []string{"ci"}, Exec: returnsNil},
+			},
+			Exec: returnsNil,
 		}
-		return nil
+	}
+
+	tests := []struct {
+		Name        string
+		PassedArgs  []string
+		Suggestions []string
+		WantErr     bool
+	}{
+		{Name: "Known", PassedArgs: []string{"ci"}},
+		{Name: "No Args", PassedArgs: []string{}},
+		{Name: "Separated", PassedArgs: []string{"--", "foo"}},
+		{Name: "Typo", PassedArgs: []string{"stats"}, Suggestions: []string{"status", "stash"}, WantErr: true},
+		{Name: "Prefix", PassedArgs: []string{"com"}, Suggestions: []string{"commit"}, WantErr: true},
+		{Name: "Alias Typo", PassedArgs: []string{"cj"}, Suggestions: []string{"commit"}, WantErr: true},
+		{Name: "Unrelated", PassedArgs: []string{"foo"}, Suggestions: []string{}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := newCommand().Parse(tt.PassedArgs)
+			if (err != nil) != tt.WantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.WantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			var unknown UnknownSubcommandError
+			if !errors.As(err, &unknown) || !errors.Is(err, ErrUnknownCommand) {
+				t.Fatalf("Parse() error = %v, want %T", err, unknown)
+			}
+			if !reflect.DeepEqual(unknown.Suggestions, tt.Suggestions) {
+				t.Errorf("Suggestions = %q, want %q", unknown.Suggestions, tt.Suggestions)
+			}
+		})
+	}
+
+	err := newCommand().Parse([]string{"stats"})
+	want := `unknown subcommand "stats" for "bar", did you mean "status", "stash"? (available: status, stash, commit)`
+	if err == nil || err.Error() != want {
+		t.Errorf("Parse() error = %v, want %s", err, want)
+	}
+}
+
+func TestCommand_NameOverride(t *testing.T) {
+	get := &Command{Usage: "tool sub get <id>", NameOverride: "get", ArgsValidator: ExactArgs(1), Exec: expectsArgs("42")}
+	cmd := Command{Usage: "tool", Subcommands: []*Command{get}}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"get", "42"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if usage := get.UsageString(); !strings.Contains(usage, " tool sub get <id>\n") {
+		t.Errorf("usage does not contain Usage:\n%s", usage)
+	}
+}
+
+func TestCommand_Parent(t *testing.T) {
+	leaf := &Command{Usage: "leaf", Exec: returnsNil}
+	mid := &Command{Usage: "mid", Subcommands: []*Command{leaf}}
+	cmd := &Command{Usage: "root", Subcommands: []*Command{mid}}
+
+	if leaf.Parent() != nil {
+		t.Errorf("Parent() before Parse = %v, want nil", leaf.Parent())
+	}
+
+	if err := cmd.Parse([]string{"mid", "leaf"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+
+	if cmd.Parent() != nil || mid.Parent() != cmd || leaf.Parent() != mid {
+		t.Errorf("Parent() = %v, %v, %v, want nil, root, mid", cmd.Parent(), mid.Parent(), leaf.Parent())
+	}
+}
+
+func TestCommand_RawArgs(t *testing.T) {
+	sub := &Command{Usage: "sub", FlagSet: flag.NewFlagSet("sub", flag.ContinueOnError), Exec: returnsNil}
+	cmd := Command{
+		Usage:       "root",
+		FlagSet:     flag.NewFlagSet("root", flag.ContinueOnError),
+		Subcommands: []*Command{sub},
+		ArgsTransform: func(args []string) []string {
+			return append([]string{}, args[1:]...)
+		},
+	}
+
+	if cmd.RawArgs() != nil {
+		t.Errorf("RawArgs() = %v before parse, want nil", cmd.RawArgs())
+	}
+
+	if err := cmd.Parse([]string{"ignored", "sub", "a", "b"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+
+	if want := []string{"ignored", "sub", "a", "b"}; !reflect.DeepEqual(cmd.RawArgs(), want) {
+		t.Errorf("root RawArgs() = %v, want %v", cmd.RawArgs(), want)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(sub.RawArgs(), want) {
+		t.Errorf("sub RawArgs() = %v, want %v", sub.RawArgs(), want)
+	}
+}
+
+func TestCommand_Middleware(t *testing.T) {
+	var calls []string
+	record := func(name string) func(next ExecFunc) ExecFunc {
+		return func(next ExecFunc) ExecFunc {
+			return func(ctx context.Context, args []string) error {
+				calls = append(calls, name)
+				return next(ctx, args)
+			}
+		}
+	}
+
+	cmd := Command{
+		Usage:      "root",
+		FlagSet:    flag.NewFlagSet("root", flag.ContinueOnError),
+		Middleware: []func(next ExecFunc) ExecFunc{record("root1"), record("root2")},
+		Subcommands: []*Command{{
+			Usage:      "sub",
+			FlagSet:    flag.NewFlagSet("sub", flag.ContinueOnError),
+			Middleware: []func(next ExecFunc) ExecFunc{record("sub")},
+			Exec: func(ctx context.Context, args []string) error {
+				calls = append(calls, "exec")
+				return nil
+			},
+		}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"sub"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := []string{"root1", "root2", "sub", "exec"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCommand_OnRun(t *testing.T) {
+	var calls []string
+	onRun := func(ctx context.Context, c *Command) {
+		calls = append(calls, c.Name())
+	}
+
+	cmd := Command{
+		Usage: "root",
+		OnRun: onRun,
+		Subcommands: []*Command{{
+			Usage: "mid",
+			Subcommands: []*Command{{
+				Usage: "leaf",
+				OnRun: onRun,
+				Exec: func(ctx context.Context, args []string) error {
+					calls = append(calls, "exec")
+					return nil
+				},
+			}},
+		}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"mid", "leaf"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := []string{"root", "leaf", "exec"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCommand_ContextDecorator(t *testing.T) {
+	type key string
+	decorate := func(k, v string) func(ctx context.Context) context.Context {
+		return func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, key(k), v)
+		}
+	}
+
+	cmd := Command{
+		Usage:            "root",
+		ContextDecorator: decorate("logger", "root"),
+		Subcommands: []*Command{{
+			Usage:            "sub",
+			ContextDecorator: decorate("config", "sub"),
+			OnRun: func(ctx context.Context, c *Command) {
+				if ctx.Value(key("config")) != "sub" {
+					t.Error("OnRun context is not decorated")
+				}
+			},
+			Exec: func(ctx context.Context, args []string) error {
+				if ctx.Value(key("logger")) != "root" || ctx.Value(key("config")) != "sub" {
+					return errors.New("context is not decorated")
+				}
+				return nil
+			},
+		}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"sub"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+}
+
+func TestCommand_UsageTruncatesShortHelp(t *testing.T) {
+	cmd := Command{
+		Usage:     "root",
+		HelpWidth: 20,
+		Subcommands: []*Command{
+			{Usage: "sub", ShortHelp: "a short help that is too long"},
+			{Usage: "other", ShortHelp: "fits"},
+		},
+	}
+
+	usage := defaultUsageFunc(&cmd)
+	if !strings.Contains(usage, "  sub    a short he…\n") {
+		t.Errorf("usage does not truncate short help:\n%s", usage)
+	}
+	if !strings.Contains(usage, "  other  fits\n") {
+		t.Errorf("usage truncates short help that fits:\n%s", usage)
+	}
+}
+
+func TestCommand_PreserveUsageWhitespace(t *testing.T) {
+	var b strings.Builder
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	fs.SetOutput(&b)
+
+	cmd := Command{
+		Usage:                   "root",
+		FlagSet:                 fs,
+		PreserveUsageWhitespace: true,
+		UsageFunc: func(c *Command) string {
+			return "\n  root usage\n\n\n"
+		},
+	}
+
+	if err := cmd.Parse([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("Parse() error = %v, want %v", err, flag.ErrHelp)
+	}
+	if want := "\n  root usage\n\n\n"; b.String() != want {
+		t.Errorf("usage = %q, want %q", b.String(), want)
+	}
+}
+
+func TestCommand_EnableArgv0Dispatch(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	tests := []struct {
+		Name       string
+		Argv0      string
+		PassedArgs []string
+		Want       string
+	}{
+		{Name: "Subcommand", Argv0: "/usr/bin/ls", PassedArgs: []string{"-l"}, Want: "ls"},
+		{Name: "Alias Exe", Argv0: "dir.exe", PassedArgs: []string{"-l"}, Want: "ls"},
+		{Name: "No Match", Argv0: "/usr/bin/box", PassedArgs: []string{"cat"}, Want: "cat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			os.Args = []string{tt.Argv0}
+
+			var ran string
+			lsFlags := flag.NewFlagSet("ls", flag.ContinueOnError)
+			_ = lsFlags.Bool("l", false, "long")
+			cmd := Command{
+				Usage:               "box",
+				EnableArgv0Dispatch: true,
+				Subcommands: []*Command{
+					{Usage: "ls", Aliases: []string{"dir"}, FlagSet: lsFlags, Exec: func(ctx context.Context, args []string) error {
+						ran = "ls"
+						return nil
+					}},
+					{Usage: "cat", Exec: func(ctx context.Context, args []string) error {
+						ran = "cat"
+						return nil
+					}},
+				},
+			}
+
+			if err := cmd.ParseAndRun(context.Background(), tt.PassedArgs); err != nil {
+				t.Fatalf("ParseAndRun() error %v", err)
+			}
+			if ran != tt.Want {
+				t.Errorf("ran %q, want %q", ran, tt.Want)
+			}
+		})
+	}
+}
+
+func TestCommand_AtLeastOneFlag(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		ErrCheck   func(error) bool
+	}{
+		{Name: "First Set", PassedArgs: []string{"-file", "a.txt"}},
+		{Name: "Second Set", PassedArgs: []string{"-url", "http://example.com"}},
+		{Name: "Alias Set", PassedArgs: []string{"-path", "a.txt"}},
+		{Name: "None Set", PassedArgs: []string{}, ErrCheck: errorIs(ErrInvalidArguments)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			_ = fs.String("file", "", "file")
+			_ = fs.String("url", "", "url")
+
+			cmd := Command{
+				Usage:          "root",
+				FlagSet:        fs,
+				FlagAliases:    map[string]string{"path": "file"},
+				AtLeastOneFlag: [][]string{{"file", "url"}, {}},
+				Exec:           returnsNil,
+			}
+
+			err := cmd.Parse(tt.PassedArgs)
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Errorf("Parse() error %v", err)
+			}
+		})
+	}
+}
+
+func TestCommand_ExecChain(t *testing.T) {
+	var calls []string
+	step := func(name string, err error) ExecFunc {
+		return func(ctx context.Context, args []string) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	errStep := errors.New("step failed")
+
+	cmd := Command{
+		Usage: "root",
+		Subcommands: []*Command{
+			{Usage: "ok", ExecChain: []ExecFunc{step("validate", nil), step("do", nil)}},
+			{Usage: "fail", ExecChain: []ExecFunc{step("validate", errStep), step("do", nil)}},
+		},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"ok"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+	if want := []string{"validate", "do"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	calls = nil
+	cmd.reset()
+	if err := cmd.ParseAndRun(context.Background(), []string{"fail"}); !errors.Is(err, errStep) {
+		t.Fatalf("ParseAndRun() error = %v, want %v", err, errStep)
+	}
+	if want := []string{"validate"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestCommand_HelpOutput(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		Exec       ExecFunc
+		WantHelp   bool
+	}{
+		{Name: "Help Flag", PassedArgs: []string{"sub", "-h"}, WantHelp: true},
+		{Name: "Exec Help", PassedArgs: []string{"sub"}, Exec: func(ctx context.Context, args []string) error {
+			return flag.ErrHelp
+		}, WantHelp: true},
+		{Name: "Exec Show Usage", PassedArgs: []string{"sub"}, Exec: func(ctx context.Context, args []string) error {
+			return ErrShowUsage
+		}, WantHelp: true},
+		{Name: "Unknown Flag", PassedArgs: []string{"sub", "-nope"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var help, errOut strings.Builder
+			fs := flag.NewFlagSet("sub", flag.ContinueOnError)
+			fs.SetOutput(&errOut)
+
+			cmd := Command{
+				Usage:       "root",
+				HelpOutput:  &help,
+				Subcommands: []*Command{{Usage: "sub", FlagSet: fs, Exec: tt.Exec}},
+			}
+			_ = cmd.ParseAndRun(context.Background(), tt.PassedArgs)
+
+			if got := strings.Contains(help.String(), "USAGE"); got != tt.WantHelp {
+				t.Errorf("usage in help output = %v, want %v", got, tt.WantHelp)
+			}
+			if got := strings.Contains(errOut.String(), "USAGE"); got == tt.WantHelp {
+				t.Errorf("usage in flag output = %v, want %v", got, !tt.WantHelp)
+			}
+		})
+	}
+}
+
+func TestCommand_OnValidationError(t *testing.T) {
+	var b, hint strings.Builder
+	fs := flag.NewFlagSet("sub", flag.ContinueOnError)
+	fs.SetOutput(&b)
+
+	cmd := Command{
+		Usage: "root",
+		OnValidationError: func(c *Command, err error) {
+			fmt.Fprintf(&hint, "%s: %v, see %s -h", c.Name(), err, c.Name())
+		},
+		Subcommands: []*Command{{Usage: "sub", FlagSet: fs, ArgsValidator: NoArgs(), Exec: returnsNil}},
+	}
+
+	err := cmd.ParseAndRun(context.Background(), []string{"sub", "arg"})
+	if !errors.Is(err, ErrInvalidArguments) {
+		t.Fatalf("ParseAndRun() error = %v, want %v", err, ErrInvalidArguments)
+	}
+
+	if b.Len() != 0 {
+		t.Errorf("usage printed:\n%s", b.String())
+	}
+	if want := fmt.Sprintf("sub: %v, see sub -h", err); hint.String() != want {
+		t.Errorf("hint = %q, want %q", hint.String(), want)
+	}
+	if cmd.Subcommands[0].OnValidationError != nil {
+		t.Error("ParseAndRun() set OnValidationError of the subcommand")
+	}
+}
+
+func TestCommand_PersistentRuns(t *testing.T) {
+	var calls []string
+	record := func(name string, err error) ExecFunc {
+		return func(ctx context.Context, args []string) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	errExec := errors.New("exec failed")
+
+	tests := []struct {
+		Name    string
+		ExecErr error
+		Want    []string
+	}{
+		{Name: "Success", Want: []string{"root pre", "mid pre", "leaf pre", "exec", "leaf post", "mid post", "root post"}},
+		{Name: "Exec Error", ExecErr: errExec, Want: []string{"root pre", "mid pre", "leaf pre", "exec"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			calls = nil
+			cmd := Command{
+				Usage:             "root",
+				PersistentPreRun:  record("root pre", nil),
+				PersistentPostRun: record("root post", nil),
+				Subcommands: []*Command{{
+					Usage:             "mid",
+					PersistentPreRun:  record("mid pre", nil),
+					PersistentPostRun: record("mid post", nil),
+					Subcommands: []*Command{{
+						Usage:             "leaf",
+						PersistentPreRun:  record("leaf pre", nil),
+						PersistentPostRun: record("leaf post", nil),
+						Exec:              record("exec", tt.ExecErr),
+					}},
+				}},
+			}
+
+			if err := cmd.ParseAndRun(context.Background(), []string{"mid", "leaf"}); !errors.Is(err, tt.ExecErr) {
+				t.Fatalf("ParseAndRun() error = %v, want %v", err, tt.ExecErr)
+			}
+			if !reflect.DeepEqual(calls, tt.Want) {
+				t.Errorf("calls = %v, want %v", calls, tt.Want)
+			}
+		})
+	}
+}
+
+func TestCommand_HideEmptyDefaults(t *testing.T) {
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = fs.String("name", "", "the name")
+	_ = fs.String("mode", "fast", "the mode")
+
+	cmd := Command{Usage: "root", FlagSet: fs, HideEmptyDefaults: true}
+	usage := defaultUsageFunc(&cmd)
+
+	if strings.Contains(usage, "-name string") {
+		t.Errorf("usage contains empty default placeholder:\n%s", usage)
+	}
+	if !strings.Contains(usage, "-mode fast") {
+		t.Errorf("usage is missing non-empty default:\n%s", usage)
+	}
+}
+
+func TestCommand_EnvPrefix(t *testing.T) {
+	t.Setenv("APP_DB_MAX_CONNS", "10")
+	t.Setenv("APP_DB_HOST", "ignored")
+	t.Setenv("APP_VERBOSE", "true")
+
+	rootFlags := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = rootFlags.Bool("verbose", false, "verbose")
+	subFlags := flag.NewFlagSet("db", flag.ContinueOnError)
+	_ = subFlags.Int("max-conns", 1, "max connections")
+	_ = subFlags.String("host", "", "host")
+
+	cmd := Command{
+		Usage:     "root",
+		FlagSet:   rootFlags,
+		EnvPrefix: "APP",
+		Subcommands: []*Command{{
+			Usage:     "db",
+			FlagSet:   subFlags,
+			EnvPrefix: "DB_",
+			Exec: combineExecs(
+				expectedFlags(rootFlags, fPair{"verbose", true}),
+				expectedFlags(subFlags, fPair{"max-conns", 10}, fPair{"host", "localhost"}),
+			),
+		}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"db", "-host", "localhost"}); err != nil {
+		t.Errorf("ParseAndRun() error %v", err)
+	}
+}
+
+func TestCommand_UsageString(t *testing.T) {
+	t.Setenv("COLUMNS", "") // the golden usage is wrapped to the default width
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = fs.String("name", "", "the name")
+	_ = fs.Bool("color", true, "colorize output")
+	_ = fs.Bool("force", false, "force it")
+
+	cmd := Command{
+		Usage:       "root [flags] <arg>",
+		ShortHelp:   "does root things",
+		FlagSet:     fs,
+		Subcommands: []*Command{{Usage: "sub", ShortHelp: "does sub things"}},
+	}
+
+	want := `USAGE
+ root [flags] <arg>
+
+does root things
+
+SUBCOMMANDS
+  sub  does sub things
+
+FLAGS
+  -color (default true)  colorize output
+  -force                 force it
+  -name string           the name
+  -h                     prints help and usage for this command or subcommand
+`
+	if got := cmd.UsageString(); got != want {
+		t.Errorf("UsageString() = %q, want %q", got, want)
+	}
+
+	cmd.UsageFunc = func(c *Command) string { return "custom" }
+	if got := cmd.UsageString(); got != "custom" {
+		t.Errorf("UsageString() = %q, want %q", got, "custom")
+	}
+}
+
+func TestCommand_FlagAliases(t *testing.T) {
+	var b strings.Builder
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	fs.SetOutput(&b)
+	_ = fs.Duration("deadline", 0, "how long to wait")
+
+	cmd := Command{
+		Usage:       "root",
+		FlagSet:     fs,
+		FlagAliases: map[string]string{"timeout": "deadline"},
+		Exec:        expectedFlags(fs, fPair{"deadline", "5s"}),
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), []string{"-timeout", "5s"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := "Flag -timeout is deprecated, use -deadline instead\n"; b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+
+	if usage := cmd.UsageString(); strings.Contains(usage, "-timeout") {
+		t.Errorf("usage contains alias:\n%s", usage)
+	}
+}
+
+func TestCommand_FailOnDeprecated(t *testing.T) {
+	fs := flag.NewFlagSet("sub", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	_ = fs.Duration("deadline", 0, "how long to wait")
+
+	cmd := Command{
+		Usage:            "root",
+		FailOnDeprecated: true,
+		Subcommands: []*Command{{
+			Usage:       "sub",
+			FlagSet:     fs,
+			FlagAliases: map[string]string{"timeout": "deadline"},
+			Exec:        returnsNil,
+		}},
+	}
+
+	err := cmd.ParseAndRun(context.Background(), []string{"sub", "-timeout", "5s"})
+	if !errors.Is(err, ErrInvalidArguments) || !strings.Contains(err.Error(), "-timeout is deprecated") {
+		t.Errorf("ParseAndRun() error = %v, want %v", err, ErrInvalidArguments)
+	}
+}
+
+func TestCommand_OnNoExec(t *testing.T) {
+	var called *Command
+	cmd := Command{
+		Usage:   "root",
+		FlagSet: flag.NewFlagSet("root", flag.ContinueOnError),
+		OnNoExec: func(c *Command) error {
+			called = c
+			return flag.ErrHelp
+		},
+		Subcommands: []*Command{{Usage: "sub", Exec: returnsNil}},
+	}
+
+	if err := cmd.ParseAndRun(context.Background(), nil); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("ParseAndRun() error = %v, want %v", err, flag.ErrHelp)
+	}
+	if called != &cmd {
+		t.Errorf("OnNoExec called with %v, want root command", called)
+	}
+}
+
+func TestCommand_RunSubcommand(t *testing.T) {
+	migrateFlags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	_ = migrateFlags.Int("steps", 0, "steps")
+
+	runs := 0
+	cmd := Command{
+		Usage: "root",
+		Subcommands: []*Command{{
+			Usage: "db",
+			Subcommands: []*Command{{
+				Usage:         "migrate",
+				Aliases:       []string{"m"},
+				FlagSet:       migrateFlags,
+				ArgsValidator: ExactArgs(1),
+				Exec: combineExecs(
+					expectedFlags(migrateFlags, fPair{"steps", 2}),
+					expectsArgs("up"),
+					func(ctx context.Context, args []string) error {
+						runs++
+						return nil
+					},
+				),
+			}},
+		}},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cmd.RunSubcommand(context.Background(), []string{"db", "m"}, []string{"-steps", "2", "up"}); err != nil {
+			t.Fatalf("RunSubcommand() error %v", err)
+		}
+	}
+	if runs != 2 {
+		t.Errorf("runs = %d, want 2", runs)
+	}
+
+	if err := cmd.RunSubcommand(context.Background(), []string{"db", "nope"}, nil); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("RunSubcommand() error = %v, want %v", err, ErrUnknownCommand)
+	}
+}
+
+func TestCommand_GlobalArgsValidator(t *testing.T) {
+	newCmd := func() *Command {
+		return &Command{
+			Usage:               "root",
+			FlagSet:             flag.NewFlagSet("root", flag.ContinueOnError),
+			GlobalArgsValidator: MaxArgs(2),
+			Subcommands: []*Command{{
+				Usage:         "sub",
+				FlagSet:       flag.NewFlagSet("sub", flag.ContinueOnError),
+				ArgsValidator: MinArgs(1),
+				Exec:          returnsNil,
+			}},
+		}
+	}
+
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		ErrCheck   func(error) bool
+	}{
+		{Name: "Both Pass", PassedArgs: []string{"sub", "a", "b"}},
+		{Name: "Own Fails", PassedArgs: []string{"sub"}, ErrCheck: errorIs(ErrInvalidArguments)},
+		{Name: "Global Fails", PassedArgs: []string{"sub", "a", "b", "c"}, ErrCheck: errorIs(ErrInvalidArguments)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := newCmd().ParseAndRun(context.Background(), tt.PassedArgs); checkError(err, tt.ErrCheck) {
+				t.Errorf("ParseAndRun() error %v", err)
+			}
+		})
+	}
+}
+
+func TestCommand_Tree(t *testing.T) {
+	cmd := Command{
+		Usage:     "root",
+		ShortHelp: "the root",
+		Subcommands: []*Command{
+			{
+				Usage:     "db",
+				Aliases:   []string{"d"},
+				ShortHelp: "database commands",
+				Subcommands: []*Command{
+					{Usage: "migrate", ShortHelp: "run migrations"},
+					{Usage: "seed"},
+				},
+			},
+			{Usage: "version", Aliases: []string{"v", "ver"}},
+		},
+	}
+
+	want := `root  the root
+├── db (d)  database commands
+│   ├── migrate  run migrations
+│   └── seed
+└── version (v, ver)
+`
+	if got := cmd.Tree(); got != want {
+		t.Errorf("Tree() = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCommand_GenDOT(t *testing.T) {
+	cmd := Command{
+		Usage:     "root",
+		ShortHelp: `the "root"`,
+		Subcommands: []*Command{
+			{
+				Usage:       "db",
+				Aliases:     []string{"d"},
+				ShortHelp:   "database commands",
+				Subcommands: []*Command{{Usage: "migrate"}},
+			},
+		},
+	}
+
+	want := `digraph "root" {
+  node [shape=box];
+  "root" [label="root\nthe \"root\""];
+  "root db" [label="db (d)\ndatabase commands"];
+  "root" -> "root db";
+  "root db migrate" [label="migrate"];
+  "root db" -> "root db migrate";
+}
+`
+	var b strings.Builder
+	if err := cmd.GenDOT(&b); err != nil {
+		t.Fatalf("GenDOT() error %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("GenDOT() = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCommand_CaptureUnknownFlags(t *testing.T) {
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = fs.String("name", "", "name")
+	_ = fs.Bool("v", false, "verbose")
+
+	cmd := Command{
+		Usage:               "root",
+		FlagSet:             fs,
+		CaptureUnknownFlags: true,
+		Exec: combineExecs(
+			expectedFlags(fs, fPair{"name", "foo"}, fPair{"v", true}),
+			expectsArgs("arg", "-after"),
+		),
+	}
+
+	passed := []string{"-other=1", "-name", "foo", "--extra", "-v", "arg", "-after"}
+	if err := cmd.ParseAndRun(context.Background(), passed); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+
+	if want := []string{"-other=1", "--extra"}; !reflect.DeepEqual(cmd.UnknownFlags(), want) {
+		t.Errorf("UnknownFlags() = %v, want %v", cmd.UnknownFlags(), want)
+	}
+}
+
+func TestCommand_CombinedShortFlags(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		Flags      []fPair
+		Args       []string
+		WantErr    bool
+	}{
+		{
+			Name:       "Bools",
+			PassedArgs: []string{"-ab", "arg"},
+			Flags:      []fPair{{"a", true}, {"b", true}, {"o", ""}},
+			Args:       []string{"arg"},
+		},
+		{
+			Name:       "Value Next Arg",
+			PassedArgs: []string{"-ao", "out", "arg"},
+			Flags:      []fPair{{"a", true}, {"b", false}, {"o", "out"}},
+			Args:       []string{"arg"},
+		},
+		{
+			Name:       "Value Attached",
+			PassedArgs: []string{"-bout", "arg"},
+			Flags:      []fPair{{"a", false}, {"b", true}, {"o", "ut"}},
+			Args:       []string{"arg"},
+		},
+		{
+			Name:       "Value Equals",
+			PassedArgs: []string{"-abo=out", "arg"},
+			Flags:      []fPair{{"a", true}, {"b", true}, {"o", "out"}},
+			Args:       []string{"arg"},
+		},
+		{
+			Name:       "Flag Name",
+			PassedArgs: []string{"-ab", "-abc", "arg"},
+			Flags:      []fPair{{"a", true}, {"b", true}, {"abc", true}},
+			Args:       []string{"arg"},
+		},
+		{
+			Name:       "Unknown Letter",
+			PassedArgs: []string{"-ax"},
+			WantErr:    true,
+		},
+		{
+			Name:       "After Args",
+			PassedArgs: []string{"arg", "-ab"},
+			Flags:      []fPair{{"a", false}, {"b", false}},
+			Args:       []string{"arg", "-ab"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			_ = fs.Bool("a", false, "a")
+			_ = fs.Bool("b", false, "b")
+			_ = fs.String("o", "", "output")
+			_ = fs.Bool("abc", false, "abc")
+
+			cmd := Command{
+				Usage:              "root",
+				FlagSet:            fs,
+				CombinedShortFlags: true,
+				Exec:               combineExecs(expectedFlags(fs, tt.Flags...), expectsArgs(tt.Args...)),
+			}
+
+			err := cmd.ParseAndRun(context.Background(), tt.PassedArgs)
+			if (err != nil) != tt.WantErr {
+				t.Errorf("ParseAndRun() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestCommand_EnableVersionCommand(t *testing.T) {
+	t.Setenv("COLUMNS", "") // the golden usage is wrapped to the default width
+	newCommand := func(out io.Writer) *Command {
+		fs := flag.NewFlagSet("root", flag.ContinueOnError)
+		fs.SetOutput(out)
+		return &Command{
+			Usage:                "root",
+			FlagSet:              fs,
+			Output:               out,
+			BuildInfo:            BuildInfo{Version: "v1.2.3", Commit: "abc123"},
+			EnableVersionCommand: true,
+		}
+	}
+
+	var b strings.Builder
+	if err := newCommand(&b).ParseAndRun(context.Background(), []string{"version", "-short"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+	if b.String() != "v1.2.3\n" {
+		t.Errorf("output = %q, want %q", b.String(), "v1.2.3\n")
+	}
+
+	b.Reset()
+	if err := newCommand(&b).ParseAndRun(context.Background(), []string{"version"}); err != nil {
+		t.Fatalf("ParseAndRun() error %v", err)
+	}
+	want := fmt.Sprintf(`Version:  v1.2.3
+Commit:   abc123
+Date:     unknown
+Go:       %s
+OS/Arch:  %s/%s
+`, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := newCommand(&b).Parse([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("Parse() error = %v, want %v", err, flag.ErrHelp)
+	}
+	if !strings.Contains(b.String(), "version  prints version and build information") {
+		t.Errorf("help = %q, want it to list the version subcommand", b.String())
+	}
+
+	if usage := newCommand(io.Discard).UsageString(); !strings.Contains(usage, "version  prints version") {
+		t.Errorf("UsageString() = %q, want it to list the version subcommand", usage)
+	}
+}
+
+func TestCommand_DisableHelpFlagUndefined(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantErr    bool
+	}{
+		{Name: "After Bool", PassedArgs: []string{"-v", "-h"}, WantErr: true},
+		{Name: "With Value", PassedArgs: []string{"--h=true"}, WantErr: true},
+		{Name: "Flag Value", PassedArgs: []string{"-name", "-h"}},
+		{Name: "Positional", PassedArgs: []string{"x", "-h"}},
+		{Name: "After Separator", PassedArgs: []string{"--", "-h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b strings.Builder
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			fs.SetOutput(&b)
+			_ = fs.Bool("v", false, "verbose")
+			_ = fs.String("name", "", "name")
+
+			cmd := Command{Usage: "root", FlagSet: fs, DisableHelpFlag: true, Exec: returnsNil}
+			err := cmd.Parse(tt.PassedArgs)
+			if fs.Lookup("h") != nil {
+				t.Error("Parse() defined -h in FlagSet")
+			}
+
+			if !tt.WantErr {
+				if err != nil {
+					t.Errorf("Parse() error %v", err)
+				}
+				return
+			}
+			if want := "flag provided but not defined: -h"; err == nil || err.Error() != want {
+				t.Errorf("Parse() error = %v, want %s", err, want)
+			}
+			if !strings.HasPrefix(b.String(), "flag provided but not defined: -h\nUSAGE\n") {
+				t.Errorf("output = %q, want the error followed by usage", b.String())
+			}
+		})
+	}
+}
+
+func TestCommand_FlagErrorHandling(t *testing.T) {
+	fs := flag.NewFlagSet("root", flag.ExitOnError)
+	fs.SetOutput(io.Discard)
+
+	cmd := Command{Usage: "root", FlagSet: fs, Exec: returnsNil}
+	if err := cmd.ParseAndRun(context.Background(), []string{"-undefined"}); err == nil {
+		t.Error("ParseAndRun() error = nil, want error")
+	}
+	if fs.ErrorHandling() != flag.ContinueOnError {
+		t.Errorf("ErrorHandling() = %v, want %v", fs.ErrorHandling(), flag.ContinueOnError)
+	}
+
+	cmd = Command{Usage: "root", FlagErrorHandling: flag.PanicOnError, Exec: returnsNil}
+	if err := cmd.Parse(nil); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+	if cmd.FlagSet.ErrorHandling() != flag.PanicOnError {
+		t.Errorf("ErrorHandling() = %v, want %v", cmd.FlagSet.ErrorHandling(), flag.PanicOnError)
+	}
+}
+
+func TestCommand_FlagErrorHandlingExit(t *testing.T) {
+	if args := os.Getenv("SCLI_TEST_EXIT_ON_ERROR"); args != "" {
+		cmd := Command{Usage: "root", FlagErrorHandling: flag.ExitOnError, Exec: returnsNil}
+		_ = cmd.Parse(strings.Fields(args))
+		os.Exit(3) // not reached when the FlagSet exits
+	}
+
+	tests := []struct {
+		Name       string
+		Args       string
+		WantCode   int
+		WantStdout string
+		WantStderr string
+	}{
+		{Name: "Help", Args: "-h", WantCode: 0, WantStdout: "USAGE\n root\n"},
+		{Name: "Undefined Flag", Args: "-bogus", WantCode: 2, WantStderr: "flag provided but not defined: -bogus\nUSAGE\n root\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			cmd := exec.Command(os.Args[0], "-test.run=^TestCommand_FlagErrorHandlingExit$")
+			cmd.Env = append(os.Environ(), "SCLI_TEST_EXIT_ON_ERROR="+tt.Args, "COLUMNS=")
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+			err := cmd.Run()
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("Run() error %v", err)
+			}
+			if code != tt.WantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.WantCode)
+			}
+			if !strings.HasPrefix(stdout.String(), tt.WantStdout) || !strings.HasPrefix(stderr.String(), tt.WantStderr) {
+				t.Errorf("output = %q, %q, want prefixes %q, %q", stdout.String(), stderr.String(), tt.WantStdout, tt.WantStderr)
+			}
+		})
+	}
+}
+
+func TestCommand_FlagChanged(t *testing.T) {
+	fs := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = fs.String("name", "default", "name")
+	_ = fs.Int("count", 0, "count")
+
+	cmd := Command{
+		Usage:       "root",
+		FlagSet:     flag.NewFlagSet("root", flag.ContinueOnError),
+		Subcommands: []*Command{{Usage: "sub", FlagSet: fs, Exec: returnsNil}},
+	}
+
+	if cmd.FlagChanged("name") {
+		t.Error("FlagChanged(name) = true before parse, want false")
+	}
+
+	if err := cmd.Parse([]string{"sub", "-name", "default"}); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+
+	if !cmd.FlagChanged("name") {
+		t.Error("FlagChanged(name) = false, want true")
+	}
+	if cmd.FlagChanged("count") {
+		t.Error("FlagChanged(count) = true, want false")
+	}
+}
+
+func TestCommand_Graft(t *testing.T) {
+	cmd := Command{
+		Usage:       "root",
+		Subcommands: []*Command{{Usage: "plugins", Subcommands: []*Command{{Usage: "list"}}}},
+	}
+
+	if err := cmd.Graft([]string{"plugins"}, &Command{Usage: "foo"}); err != nil {
+		t.Fatalf("Graft() error %v", err)
+	}
+	if _, err := cmd.LookupCommand("plugins", "foo"); err != nil {
+		t.Errorf("LookupCommand() error %v", err)
+	}
+
+	if err := cmd.Graft([]string{"plugins"}, &Command{Usage: "bar", Aliases: []string{"list"}}); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("Graft() error = %v, want %v", err, ErrDuplicateCommand)
+	}
+	if err := cmd.Graft([]string{"missing"}, &Command{Usage: "bar"}); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Graft() error = %v, want %v", err, ErrUnknownCommand)
+	}
+}
+
+func TestCommand_BufferOutput(t *testing.T) {
+	tests := []struct {
+		Name string
+		Err  error
+		Want string
+	}{
+		{Name: "Success", Err: nil, Want: "partial output"},
+		{Name: "Failure", Err: errors.New("failed"), Want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var b strings.Builder
+			cmd := Command{
+				Usage:        "root",
+				FlagSet:      flag.NewFlagSet("root", flag.ContinueOnError),
+				Output:       &b,
+				BufferOutput: true,
+				Exec: func(ctx context.Context, args []string) error {
+					_, _ = fmt.Fprint(OutputFromContext(ctx), "partial output")
+					if b.Len() != 0 {
+						t.Error("output written before Exec returned")
+					}
+					return tt.Err
+				},
+			}
+
+			if err := cmd.ParseAndRun(context.Background(), nil); err != tt.Err {
+				t.Errorf("ParseAndRun() error = %v, want %v", err, tt.Err)
+			}
+			if b.String() != tt.Want {
+				t.Errorf("output = %q, want %q", b.String(), tt.Want)
+			}
+		})
+	}
+}
+
+type typedValue struct{ s string }
+
+func (v *typedValue) String() string     { return v.s }
+func (v *typedValue) Set(s string) error { v.s = s; return nil }
+func (v *typedValue) Type() string       { return "path" }
+
+type kindValue int
+
+func (v *kindValue) String() string     { return "" }
+func (v *kindValue) Set(s string) error { return nil }
+
+func TestFlagType(t *testing.T) {
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	_ = fs.Int("int", 0, "an int")
+	_ = fs.Duration("duration", 0, "a duration")
+	_ = fs.String("file", "", "path to the `config` file")
+	fs.Var(&typedValue{}, "typed", "a typed value")
+	fs.Var(new(kindValue), "kind", "a kind value")
+
+	tests := map[string]string{
+		"int":      "int",
+		"duration": "duration",
+		"file":     "config",
+		"typed":    "path",
+		"kind":     "int",
+	}
+
+	for name, want := range tests {
+		if got := flagType(fs.Lookup(name)); got != want {
+			t.Errorf("flagType(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCommand_LookupCommandPrefix(t *testing.T) {
+	cmd := Command{
+		Usage: "root",
+		Subcommands: []*Command{
+			{Usage: "status", Subcommands: []*Command{{Usage: "verbose"}}},
+			{Usage: "stash"},
+			{Usage: "st"},
+			{Usage: "commit", Aliases: []string{"ci"}},
+		},
+	}
+
+	tests := []struct {
+		Name     string
+		Path     []string
+		Want     string
+		ErrCheck func(error) bool
+	}{
+		{Name: "Exact", Path: []string{"st"}, Want: "st"},
+		{Name: "Unique Prefix", Path: []string{"stat", "v"}, Want: "verbose"},
+		{Name: "Alias Prefix", Path: []string{"c"}, Want: "commit"},
+		{Name: "Ambiguous", Path: []string{"sta"}, ErrCheck: errorIs(ErrAmbiguousCommand)},
+		{Name: "Unknown", Path: []string{"push"}, ErrCheck: errorIs(ErrUnknownCommand)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := cmd.LookupCommandPrefix(tt.Path...)
+			if checkError(err, tt.ErrCheck) {
+				t.Fatalf("LookupCommandPrefix() error %v", err)
+			}
+			if err == nil && got.Name() != tt.Want {
+				t.Errorf("LookupCommandPrefix() = %s, want %s", got.Name(), tt.Want)
+			}
+		})
+	}
+}
+
+func returnsNil(_ context.Context, _ []string) error {
+	return nil
+}
+
+func combineExecs(execs ...func(ctx context.Context, args []string) error) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		for _, f := range execs {
+			if err := f(ctx, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type fPair struct {
+	Name  string
+	Value any
+}
+
+func expectedFlags(flagSet *flag.FlagSet, flagPairs ...fPair) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		fvps := make(map[string]flag.Value)
+
+		flagSet.VisitAll(func(f *flag.Flag) {
+			fvps[f.Name] = f.Value
+		})
+
+		for _, fp := range flagPairs {
+			fv, ok := fvps[fp.Name]
+
+			if !ok {
+				return fmt.Errorf("missing flag for %s", fp.Name)
+			}
+
+			s := fmt.Sprintf("%v", fp.Value)
+			if s != fv.String() {
+				return fmt.Errorf("invalid flag value for %s", fp.Name)
+			}
+		}
+		return nil
+	}
+}
+
+func expectsArgs(wantedArgs ...string) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if !reflect.DeepEqual(wantedArgs, args) {
+			return fmt.Errorf("expected args = %v, got = %v", wantedArgs, args)
+		}
+		return nil
+	}
+}
+
+func TestCommand_ParseAndValidate(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		ErrCheck   func(err error) bool
+	}{
+		{Name: "Valid", PassedArgs: []string{"sub", "a"}},
+		{Name: "Invalid Args", PassedArgs: []string{"sub"}, ErrCheck: errorIs(ErrInvalidArguments)},
+		{Name: "Invalid Flag", PassedArgs: []string{"-nope", "sub", "a"}, ErrCheck: func(err error) bool {
+			return !errors.Is(err, ErrInvalidArguments)
+		}},
+		{Name: "No Exec", PassedArgs: []string{}, ErrCheck: errorAs[NoExecError]()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+			subFlags.SetOutput(io.Discard)
+
+			cmd := &Command{
+				Usage:   "root",
+				FlagSet: fs,
+				Subcommands: []*Command{{
+					Usage:         "sub",
+					FlagSet:       subFlags,
+					ArgsValidator: ExactArgs(1),
+					Exec: func(ctx context.Context, args []string) error {
+						t.Error("ParseAndValidate() ran Exec")
+						return nil
+					},
+				}},
+			}
+
+			err := cmd.ParseAndValidate(tt.PassedArgs)
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Errorf("ParseAndValidate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCommand_Timeout(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Timeout    time.Duration
+		WantLimit  time.Duration // 0 for no deadline
+		WaitForEnd bool
+		ErrCheck   func(err error) bool
+	}{
+		{Name: "Inherited", WantLimit: time.Millisecond, WaitForEnd: true, ErrCheck: errorIs(ErrTimeout)},
+		{Name: "Override", Timeout: time.Hour, WantLimit: time.Hour},
+		{Name: "Disabled", Timeout: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cmd := &Command{
+				Usage:   "root",
+				Timeout: time.Millisecond,
+				Subcommands: []*Command{{
+					Usage:   "sub",
+					Timeout: tt.Timeout,
+					Exec: func(ctx context.Context, args []string) error {
+						deadline, ok := ctx.Deadline()
+						if ok != (tt.WantLimit != 0) || ok && time.Until(deadline) > tt.WantLimit {
+							t.Errorf("deadline = %v, %v, want within %s", deadline, ok, tt.WantLimit)
+						}
+						if tt.WaitForEnd {
+							<-ctx.Done()
+							return ctx.Err()
+						}
+						return nil
+					},
+				}},
+			}
+
+			err := cmd.ParseAndRun(context.Background(), []string{"sub"})
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Errorf("ParseAndRun() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCommand_AliasCommands(t *testing.T) {
+	tests := []struct {
+		Name       string
+		PassedArgs []string
+		WantEnv    string
+		WantArgs   []string
+	}{
+		{Name: "Alias", PassedArgs: []string{"deploy-prod", "app"}, WantEnv: "prod", WantArgs: []string{"app"}},
+		{Name: "Alias Flags Overridden", PassedArgs: []string{"deploy-prod", "-env", "qa"}, WantEnv: "qa", WantArgs: []string{}},
+		{Name: "Subcommand", PassedArgs: []string{"deploy", "app"}, WantEnv: "dev", WantArgs: []string{"app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+			env := fs.String("env", "dev", "the environment")
+
+			var got []string
+			cmd := &Command{
+				Usage:         "mytool",
+				AliasCommands: map[string][]string{"deploy-prod": {"deploy", "-env", "prod"}},
+				Subcommands: []*Command{{
+					Usage:   "deploy",
+					FlagSet: fs,
+					Exec: func(ctx context.Context, args []string) error {
+						got = args
+						return nil
+					},
+				}},
+			}
+
+			if err := cmd.ParseAndRun(context.Background(), tt.PassedArgs); err != nil {
+				t.Fatalf("ParseAndRun() error %v", err)
+			}
+			if *env != tt.WantEnv || !reflect.DeepEqual(got, tt.WantArgs) {
+				t.Errorf("env, args = %s, %q, want %s, %q", *env, got, tt.WantEnv, tt.WantArgs)
+			}
+		})
+	}
+}
+
+func TestCommand_ArgSpec(t *testing.T) {
+	tests := []struct {
+		Name       string
+		ArgSpec    []ArgDef
+		PassedArgs []string
+		WantUsage  string
+		ErrCheck   func(err error) bool
+	}{
+		{
+			Name:       "Optional",
+			ArgSpec:    []ArgDef{{Name: "src", Required: true}, {Name: "dest"}},
+			PassedArgs: []string{"a"},
+			WantUsage:  "copy [flags] <src> [dest]",
+		},
+		{
+			Name:       "Too Few",
+			ArgSpec:    []ArgDef{{Name: "src", Required: true}, {Name: "dest"}},
+			PassedArgs: []string{},
+			WantUsage:  "copy [flags] <src> [dest]",
+			ErrCheck:   errorIs(ErrInvalidArguments),
+		},
+		{
+			Name:       "Too Many",
+			ArgSpec:    []ArgDef{{Name: "src", Required: true}, {Name: "dest"}},
+			PassedArgs: []string{"a", "b", "c"},
+			WantUsage:  "copy [flags] <src> [dest]",
+			ErrCheck:   errorIs(ErrInvalidArguments),
+		},
+		{
+			Name:       "Variadic",
+			ArgSpec:    []ArgDef{{Name: "src", Required: true}, {Name: "dest"}, {Name: "extra", Variadic: true}},
+			PassedArgs: []string{"a", "b", "c", "d"},
+			WantUsage:  "copy [flags] <src> [dest] [extra...]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			cmd := &Command{Usage: "copy [flags]", FlagSet: fs, ArgSpec: tt.ArgSpec, Exec: returnsNil}
+
+			if got := cmd.HelpSections().Usage; got != tt.WantUsage {
+				t.Errorf("Usage = %q, want %q", got, tt.WantUsage)
+			}
+
+			err := cmd.Parse(tt.PassedArgs)
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Errorf("Parse() error = %v", err)
+			}
+		})
+	}
+
+	cmd := &Command{Usage: "copy", ArgSpec: []ArgDef{{Name: "extra", Variadic: true}, {Name: "dest"}}, Exec: returnsNil}
+	if err := cmd.Validate(); err == nil {
+		t.Error("Validate() error = nil for a variadic arg before the last")
+	}
+}
+
+func TestCommand_ErrorMapper(t *testing.T) {
+	errInternal := errors.New("internal: connection refused")
+	errFriendly := errors.New("could not reach the server")
+
+	tests := []struct {
+		Name     string
+		Exec     ExecFunc
+		Mapper   func(err error) error
+		ErrCheck func(err error) bool
+	}{
+		{
+			Name: "Inherited",
+			Exec: func(ctx context.Context, args []string) error {
+				return errInternal
+			},
+			ErrCheck: errorIs(errFriendly),
+		},
+		{
+			Name: "Override",
+			Exec: func(ctx context.Context, args []string) error {
+				return errInternal
+			},
+			Mapper: func(err error) error {
+				return fmt.Errorf("sub: %w", err)
+			},
+			ErrCheck: errorIs(errInternal),
+		},
+		{Name: "Success", Exec: returnsNil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cmd := &Command{
+				Usage: "root",
+				ErrorMapper: func(err error) error {
+					if errors.Is(err, errInternal) {
+						return errFriendly
+					}
+					return err
+				},
+				Subcommands: []*Command{{Usage: "sub", Exec: tt.Exec, ErrorMapper: tt.Mapper}},
+			}
+
+			err := cmd.ParseAndRun(context.Background(), []string{"sub"})
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Errorf("ParseAndRun() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCommand_Enabled(t *testing.T) {
+	type flagKey struct{}
+	enabled := func(ctx context.Context) bool {
+		on, _ := ctx.Value(flagKey{}).(bool)
+		return on
+	}
+
+	newCommand := func() *Command {
+		return &Command{
+			Usage: "root",
+			Subcommands: []*Command{
+				{Usage: "stable", Exec: returnsNil},
+				{Usage: "beta", Enabled: enabled, Exec: returnsNil},
+			},
+		}
+	}
+
+	on := context.WithValue(context.Background(), flagKey{}, true)
+	if err := newCommand().ParseAndRun(on, []string{"beta"}); err != nil {
+		t.Errorf("ParseAndRun() error = %v, want nil", err)
+	}
+
+	cmd := newCommand()
+	err := cmd.ParseAndRun(context.Background(), []string{"beta"})
+	if !errors.Is(err, ErrUnknownCommand) || !strings.Contains(err.Error(), "(available: stable)") {
+		t.Errorf("ParseAndRun() error = %v, want %v", err, ErrUnknownCommand)
+	}
+
+	if subs := cmd.HelpSections().Subcommands; len(subs) != 1 || subs[0].Name != "stable" {
+		t.Errorf("HelpSections().Subcommands = %+v, want only stable", subs)
+	}
+
+	if got := cmd.ListCommands(); !reflect.DeepEqual(got, []string{"stable"}) {
+		t.Errorf("ListCommands() = %q, want only stable", got)
+	}
+
+	if _, err := cmd.LookupCommandPrefix("be"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("LookupCommandPrefix() error = %v, want %v", err, ErrUnknownCommand)
+	}
+
+	if got := cmd.completionWords(); !reflect.DeepEqual(got, []string{"stable", "-h"}) {
+		t.Errorf("completionWords() = %q, want only stable and -h", got)
+	}
+}
+
+func TestCommand_StopAtSubcommand(t *testing.T) {
+	tests := []struct {
+		Name        string
+		PassedArgs  []string
+		WantVerbose bool
+		WantArgs    []string
+	}{
+		{Name: "Untouched", PassedArgs: []string{"-v", "run", "-v", "--x", "--", "y"}, WantVerbose: true, WantArgs: []string{"-v", "--x", "--", "y"}},
+		{Name: "Flag Value", PassedArgs: []string{"-name=run", "run", "-h"}, WantArgs: []string{"-h"}},
+		{Name: "No Args", PassedArgs: []string{"run"}, WantArgs: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			fs := flag.NewFlagSet("root", flag.ContinueOnError)
+			verbose := fs.Bool("v", false, "verbose")
+			_ = fs.String("name", "", "name")
+			subFlags := flag.NewFlagSet("run", flag.ContinueOnError)
+			_ = subFlags.Bool("v", false, "verbose")
+
+			var got []string
+			cmd := &Command{
+				Usage:            "root",
+				FlagSet:          fs,
+				StopAtSubcommand: true,
+				Subcommands: []*Command{{
+					Usage:   "run",
+					FlagSet: subFlags,
+					Exec: func(ctx context.Context, args []string) error {
+						got = args
+						return nil
+					},
+				}},
+			}
+
+			if err := cmd.ParseAndRun(context.Background(), tt.PassedArgs); err != nil {
+				t.Fatalf("ParseAndRun() error %v", err)
+			}
+			if *verbose != tt.WantVerbose || !reflect.DeepEqual(got, tt.WantArgs) {
+				t.Errorf("verbose, args = %v, %q, want %v, %q", *verbose, got, tt.WantVerbose, tt.WantArgs)
+			}
+			if subFlags.Lookup("v").Value.String() != "false" {
+				t.Error("StopAtSubcommand parsed the flags of the subcommand")
+			}
+		})
 	}
 }
 
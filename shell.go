@@ -0,0 +1,95 @@
+package scli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// RunShell runs c as an interactive shell, reading a command line from r after each prompt written to w. Lines are
+// split with Tokenize and parsed and run as the args of c, with errors written to w by HandleError rather than
+// ending the shell. The shell ends at the end of r, when the context is done, or on a line of exit or quit that does
+// not select a subcommand.
+//
+// Entering a command that has Subcommands but no Exec or OnNoExec prints its usage as Parse does and lists its
+// enabled subcommands with numbers, and a line that is only one of those numbers runs the command line followed by that
+// subcommand. Any other line is run as a new command line. Flags keep the values set by previous lines unless they
+// are set again.
+//
+//goland:noinspection GoUnhandledErrorResult
+func (c *Command) RunShell(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+
+	// the command line and command of the last namespace entered, awaiting a numbered selection
+	var pendingArgs []string
+	var pending *Command
+	var pendingSubs []*Command
+
+	for {
+		fmt.Fprintf(w, "%s> ", c.Name())
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		args, err := Tokenize(line)
+		if err != nil {
+			c.HandleError(w, err)
+			continue
+		}
+
+		if pending != nil {
+			if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(pendingSubs) {
+				name := strings.TrimPrefix(pendingSubs[n-1].Name(), pending.SubcommandNamePrefix)
+				args = append(pendingArgs, name)
+			}
+			pendingArgs, pending, pendingSubs = nil, nil, nil
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+		if (args[0] == "exit" || args[0] == "quit") && c.subcommand(args[0]) == nil {
+			return nil
+		}
+
+		c.reset()
+		err = c.ParseContext(ctx, args)
+		var noExec NoExecError
+		var subs []*Command
+		if errors.As(err, &noExec) {
+			subs = noExec.Command.enabledSubcommands()
+		}
+		if len(subs) > 0 {
+			selected := noExec.Command
+			tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+			for i, sub := range subs {
+				name := strings.TrimPrefix(sub.Name(), selected.SubcommandNamePrefix)
+				fmt.Fprintf(tw, "  %d) %s\t%s\n", i+1, name, sub.ShortHelp)
+			}
+			tw.Flush()
+			pendingArgs, pending, pendingSubs = args, selected, subs
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				c.HandleError(w, err)
+			}
+			continue
+		}
+
+		if err := c.Run(ctx); err != nil && !errors.Is(err, flag.ErrHelp) {
+			c.HandleError(w, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
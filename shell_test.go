@@ -0,0 +1,57 @@
+package scli
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCommand_RunShell(t *testing.T) {
+	var ran []string
+	record := func(name string) ExecFunc {
+		return func(ctx context.Context, args []string) error {
+			ran = append(ran, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+			return nil
+		}
+	}
+
+	cmd := &Command{
+		Usage: "app",
+		Subcommands: []*Command{
+			{Usage: "version", Exec: record("version")},
+			{
+				Usage: "db",
+				Subcommands: []*Command{
+					{Usage: "migrate", ShortHelp: "run migrations", Exec: record("migrate")},
+					{Usage: "drop", ShortHelp: "drop tables", Enabled: func(ctx context.Context) bool { return false }, Exec: record("drop")},
+					{Usage: "seed", ShortHelp: "seed data", Exec: record("seed")},
+				},
+			},
+		},
+	}
+
+	input := strings.Join([]string{
+		"version 'a b'",
+		"db",
+		"2",
+		"db",
+		"version",
+		"nope",
+		"exit",
+		"version",
+	}, "\n")
+
+	var out strings.Builder
+	if err := cmd.RunShell(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("RunShell() error %v", err)
+	}
+
+	want := []string{"version a b", "seed", "version"}
+	if strings.Join(ran, ",") != strings.Join(want, ",") {
+		t.Errorf("ran = %q, want %q", ran, want)
+	}
+
+	if !strings.Contains(out.String(), "  1) migrate  run migrations\n  2) seed     seed data\n") {
+		t.Errorf("output = %q, want numbered subcommands", out.String())
+	}
+}
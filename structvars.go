@@ -0,0 +1,200 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// StructVars defines a flag on fs for each field of the struct pointed to by v that has a flag tag, storing the value
+// of the flag in the field. The tags of a field are:
+//
+//	flag:"name"         the name of the flag, fields without it or with "-" are skipped
+//	usage:"text"        the usage of the flag
+//	default:"value"     the default value of the flag, parsed like a value from the command line
+//	oneof:"json,yaml"   a comma separated list of the only values the flag accepts
+//
+// The default of a flag is its default tag if present, otherwise the value the field holds when StructVars is called,
+// so a struct can be initialized with its defaults before binding it. Fields left unset default to their zero value.
+//
+// Fields may be strings, bools, ints, uints, floats, time.Durations, including named types of these, or types whose
+// pointer implements flag.Value. An error is returned if v is not a pointer to a struct, a field has an unsupported
+// type or its tags are malformed.
+func StructVars(fs *flag.FlagSet, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("StructVars requires a pointer to a struct, received %T", v)
+	}
+	rv = rv.Elem()
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		name, ok := field.Tag.Lookup("flag")
+		if !ok || name == "-" {
+			continue
+		}
+		if name == "" || !field.IsExported() {
+			return fmt.Errorf("StructVars field %s: flag tag requires a name and an exported field", field.Name)
+		}
+
+		value, err := structFieldValue(rv.Field(i))
+		if err != nil {
+			return fmt.Errorf("StructVars field %s: %w", field.Name, err)
+		}
+
+		if oneof, ok := field.Tag.Lookup("oneof"); ok {
+			if value, err = newOneofValue(value, oneof); err != nil {
+				return fmt.Errorf("StructVars field %s: %w", field.Name, err)
+			}
+		}
+
+		if def, ok := field.Tag.Lookup("default"); ok {
+			if err := value.Set(def); err != nil {
+				return fmt.Errorf("StructVars field %s: invalid default %q: %w", field.Name, def, err)
+			}
+		}
+
+		fs.Var(value, name, field.Tag.Get("usage"))
+	}
+	return nil
+}
+
+// structFieldValue returns a flag.Value that sets the field v.
+func structFieldValue(v reflect.Value) (flag.Value, error) {
+	if value, ok := v.Addr().Interface().(flag.Value); ok {
+		return value, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fieldValue{v}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+// fieldValue is a flag.Value that parses values into a struct field of a builtin kind.
+type fieldValue struct {
+	v reflect.Value
+}
+
+func (f fieldValue) String() string {
+	if !f.v.IsValid() {
+		return ""
+	}
+	if f.v.Type() == durationType {
+		return time.Duration(f.v.Int()).String()
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+func (f fieldValue) Set(s string) error {
+	switch f.v.Kind() {
+	case reflect.String:
+		f.v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f.v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			f.v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 0, f.v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, f.v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.v.SetFloat(n)
+	}
+	return nil
+}
+
+func (f fieldValue) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
+
+// Type is the name of the value in the usage, matching the builtin flags of the flag package.
+func (f fieldValue) Type() string {
+	switch {
+	case !f.v.IsValid() || f.v.Kind() == reflect.Bool:
+		return ""
+	case f.v.Type() == durationType:
+		return "duration"
+	case f.v.Kind() == reflect.Float32 || f.v.Kind() == reflect.Float64:
+		return "float"
+	case f.v.Kind() == reflect.String:
+		return "string"
+	case f.v.CanInt():
+		return "int"
+	default:
+		return "uint"
+	}
+}
+
+// oneofValue wraps a flag.Value to only accept a fixed set of values.
+type oneofValue struct {
+	flag.Value
+	allowed []string
+}
+
+// newOneofValue wraps value to only accept the comma separated values of list.
+func newOneofValue(value flag.Value, list string) (flag.Value, error) {
+	if isBoolFlag(&flag.Flag{Value: value}) {
+		return nil, fmt.Errorf("oneof tag cannot be used with a bool flag")
+	}
+
+	allowed := strings.Split(list, ",")
+	for i, s := range allowed {
+		allowed[i] = strings.TrimSpace(s)
+		if allowed[i] == "" {
+			return nil, fmt.Errorf("malformed oneof tag %q, values must not be empty", list)
+		}
+	}
+	return oneofValue{value, allowed}, nil
+}
+
+func (o oneofValue) String() string {
+	if o.Value == nil {
+		return ""
+	}
+	return o.Value.String()
+}
+
+func (o oneofValue) Set(s string) error {
+	for _, allowed := range o.allowed {
+		if s == allowed {
+			return o.Value.Set(s)
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(o.allowed, ", "))
+}
+
+func (o oneofValue) Type() string {
+	return flagType(&flag.Flag{Value: o.Value})
+}
@@ -0,0 +1,94 @@
+package scli
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStructVars(t *testing.T) {
+	type format string
+	type config struct {
+		Name    string        `flag:"name" usage:"the name"`
+		Verbose bool          `flag:"verbose"`
+		Count   int           `flag:"count" default:"3"`
+		Ratio   float64       `flag:"ratio"`
+		Timeout time.Duration `flag:"timeout" default:"5s"`
+		Size    ByteSize      `flag:"size" default:"1MiB"`
+		Format  format        `flag:"format" default:"json" oneof:"json,yaml"`
+		Skipped string
+		Ignored string `flag:"-"`
+	}
+
+	cfg := config{Ratio: 0.25, Count: 1}
+	fs := flag.NewFlagSet("root", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := StructVars(fs, &cfg); err != nil {
+		t.Fatalf("StructVars() error %v", err)
+	}
+
+	want := config{Count: 3, Ratio: 0.25, Timeout: 5 * time.Second, Size: 1 << 20, Format: "json"}
+	if cfg != want {
+		t.Errorf("defaults = %+v, want %+v", cfg, want)
+	}
+	if def := fs.Lookup("ratio").DefValue; def != "0.25" {
+		t.Errorf("DefValue = %s, want 0.25", def)
+	}
+	if usage := fs.Lookup("name").Usage; usage != "the name" {
+		t.Errorf("usage = %q, want %q", usage, "the name")
+	}
+	if fs.Lookup("Skipped") != nil || fs.Lookup("Ignored") != nil || fs.Lookup("-") != nil {
+		t.Error("StructVars() defined a flag for an untagged field")
+	}
+
+	args := []string{"-name", "foo", "-verbose", "-count", "7", "-ratio", "0.5", "-timeout", "1m", "-size", "2KB", "-format", "yaml"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse() error %v", err)
+	}
+
+	want = config{Name: "foo", Verbose: true, Count: 7, Ratio: 0.5, Timeout: time.Minute, Size: 2000, Format: "yaml"}
+	if cfg != want {
+		t.Errorf("parsed = %+v, want %+v", cfg, want)
+	}
+
+	if err := fs.Set("format", "toml"); err == nil {
+		t.Error("Set() error = nil for a value not in oneof")
+	}
+}
+
+func TestStructVars_Errors(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Value any
+	}{
+		{Name: "Not Pointer", Value: struct{}{}},
+		{Name: "Not Struct", Value: new(string)},
+		{Name: "Unsupported Type", Value: &struct {
+			Tags []string `flag:"tags"`
+		}{}},
+		{Name: "Empty Name", Value: &struct {
+			Name string `flag:""`
+		}{}},
+		{Name: "Empty Oneof", Value: &struct {
+			Format string `flag:"format" oneof:"json,,yaml"`
+		}{}},
+		{Name: "Bool Oneof", Value: &struct {
+			Verbose bool `flag:"verbose" oneof:"true"`
+		}{}},
+		{Name: "Default Not In Oneof", Value: &struct {
+			Format string `flag:"format" default:"toml" oneof:"json,yaml"`
+		}{}},
+		{Name: "Bad Default", Value: &struct {
+			Count int `flag:"count" default:"many"`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := StructVars(flag.NewFlagSet("root", flag.ContinueOnError), tt.Value); err == nil {
+				t.Error("StructVars() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package scli
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestionDistance is the largest edit distance between an unknown name and a subcommand for it to be suggested.
+const maxSuggestionDistance = 2
+
+// suggestSubcommands returns the names of the subcommands of c that name may be a misspelling of, ordered by how
+// similar they are. A subcommand is suggested if name is a prefix of it, or any of its names or aliases are within
+// maxSuggestionDistance edits of name, ignoring case.
+func (c *Command) suggestSubcommands(name string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	name = strings.ToLower(name)
+	var suggestions []suggestion
+	for _, sub := range c.Subcommands {
+		if !c.subcommandEnabled(sub) {
+			continue
+		}
+		best := -1
+		for _, s := range sub.names() {
+			s = strings.ToLower(strings.TrimPrefix(s, c.SubcommandNamePrefix))
+			d := levenshtein(name, s)
+			if strings.HasPrefix(s, name) {
+				d = 0
+			}
+			if d <= maxSuggestionDistance && (best < 0 || d < best) {
+				best = d
+			}
+		}
+
+		if best >= 0 {
+			suggestions = append(suggestions, suggestion{strings.TrimPrefix(sub.Name(), c.SubcommandNamePrefix), best})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	names := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		names[i] = s.name
+	}
+	return names
+}
+
+// levenshtein returns the number of single character insertions, deletions or substitutions needed to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,99 @@
+package scli
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultTerminalWidth = 80
+
+// terminalWidth returns the width of the terminal that w writes to. If w is not a terminal the COLUMNS environment
+// variable is used, falling back to defaultTerminalWidth if it is not a positive number.
+func terminalWidth(w io.Writer) int {
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		if width, ok := terminalSize(f.Fd()); ok && width > 0 {
+			return width
+		}
+	}
+	return envSize("COLUMNS", defaultTerminalWidth)
+}
+
+// envSize returns the positive number in the environment variable name, or def if it is unset or invalid.
+func envSize(name string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name))); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// isTerminal reports whether v is a file open on a terminal.
+func isTerminal(v any) bool {
+	f, ok := v.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	_, ok = terminalSize(f.Fd())
+	return ok
+}
+
+// helpWidth returns the width the default usage of c should be wrapped to, or 0 if it should not be wrapped.
+func (c *Command) helpWidth() int {
+	switch {
+	case c.HelpWidth > 0:
+		return c.HelpWidth
+	case c.HelpWidth < 0:
+		return 0
+	case c.FlagSet != nil:
+		return terminalWidth(c.FlagSet.Output())
+	default:
+		return terminalWidth(os.Stderr)
+	}
+}
+
+// wrapText wraps each line of s so that it is no wider than width, breaking between words.
+// Lines that already fit are left unchanged, and words longer than width are left on a line of their own.
+// A width of 0 or less returns s unchanged.
+func wrapText(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if len(line) <= width {
+			continue // left as is to preserve any deliberate spacing
+		}
+
+		var b strings.Builder
+		n := 0
+		for _, word := range strings.Fields(line) {
+			switch {
+			case n == 0:
+			case n+1+len(word) > width:
+				b.WriteByte('\n')
+				n = 0
+			default:
+				b.WriteByte(' ')
+				n++
+			}
+			b.WriteString(word)
+			n += len(word)
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateText shortens s to at most width runes, replacing the end with an ellipsis if it is cut.
+// A width of 0 or less returns s unchanged.
+func truncateText(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	return strings.TrimRight(string(runes[:width-1]), " ") + "…"
+}
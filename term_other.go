@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package scli
+
+// terminalSize always reports that fd is not a terminal on platforms without TIOCGWINSZ.
+func terminalSize(fd uintptr) (width int, ok bool) {
+	return 0, false
+}
@@ -0,0 +1,68 @@
+package scli
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	if got := terminalWidth(&bytes.Buffer{}); got != defaultTerminalWidth {
+		t.Errorf("terminalWidth() = %d, want %d", got, defaultTerminalWidth)
+	}
+
+	t.Setenv("COLUMNS", "120")
+	if got := terminalWidth(&bytes.Buffer{}); got != 120 {
+		t.Errorf("terminalWidth() = %d, want 120", got)
+	}
+
+	t.Setenv("COLUMNS", "wide")
+	if got := terminalWidth(&bytes.Buffer{}); got != defaultTerminalWidth {
+		t.Errorf("terminalWidth() = %d, want %d", got, defaultTerminalWidth)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Text  string
+		Width int
+		Want  string
+	}{
+		{Name: "Fits", Text: "  keep   spacing", Width: 20, Want: "  keep   spacing"},
+		{Name: "Wraps", Text: "the quick brown fox jumps", Width: 10, Want: "the quick\nbrown fox\njumps"},
+		{Name: "Long Word", Text: "a verylongword b", Width: 5, Want: "a\nverylongword\nb"},
+		{Name: "Lines", Text: "one two three\nfour", Width: 8, Want: "one two\nthree\nfour"},
+		{Name: "Disabled", Text: "the quick brown fox", Width: 0, Want: "the quick brown fox"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if got := wrapText(tt.Text, tt.Width); got != tt.Want {
+				t.Errorf("wrapText() = %q, want %q", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Text  string
+		Width int
+		Want  string
+	}{
+		{Name: "Fits", Text: "short help", Width: 10, Want: "short help"},
+		{Name: "Truncated", Text: "a long short help", Width: 8, Want: "a long…"},
+		{Name: "Runes", Text: "héllo wörld", Width: 5, Want: "héll…"},
+		{Name: "Disabled", Text: "a long short help", Width: 0, Want: "a long short help"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if got := truncateText(tt.Text, tt.Width); got != tt.Want {
+				t.Errorf("truncateText() = %q, want %q", got, tt.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package scli
+
+import (
+	"errors"
+	"strings"
+)
+
+// Tokenize splits line into args like a POSIX shell, without any expansion. Args are separated by unquoted
+// whitespace, single quotes preserve everything up to the closing quote, double quotes preserve everything except
+// for the escapes \", \\, \$ and \`, and a backslash outside of quotes escapes the following character.
+// An error is returned for an unterminated quote or a trailing backslash.
+func Tokenize(line string) ([]string, error) {
+	var (
+		args    []string
+		b       strings.Builder
+		inToken bool // distinguishes an empty quoted arg from no arg
+	)
+
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; ch {
+		case ' ', '\t', '\n', '\r':
+			if inToken {
+				args = append(args, b.String())
+				b.Reset()
+				inToken = false
+			}
+		case '\\':
+			if i+1 == len(line) {
+				return nil, errors.New("unterminated escape at end of line")
+			}
+			i++
+			if line[i] != '\n' { // an escaped newline continues the line
+				b.WriteByte(line[i])
+				inToken = true
+			}
+		case '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			b.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		case '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`\n", line[i+1]) >= 0 {
+					i++
+					if line[i] == '\n' {
+						continue
+					}
+				}
+				b.WriteByte(line[i])
+			}
+			if i == len(line) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inToken = true
+		default:
+			b.WriteByte(ch)
+			inToken = true
+		}
+	}
+
+	if inToken {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,40 @@
+package scli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Line    string
+		Want    []string
+		WantErr bool
+	}{
+		{Name: "Empty", Line: "  ", Want: nil},
+		{Name: "Fields", Line: " deploy  -env prod\tapp ", Want: []string{"deploy", "-env", "prod", "app"}},
+		{Name: "Single Quotes", Line: `echo 'a "b" \c'`, Want: []string{"echo", `a "b" \c`}},
+		{Name: "Double Quotes", Line: `echo "a 'b' \"c\" \n \\"`, Want: []string{"echo", `a 'b' "c" \n \`}},
+		{Name: "Escapes", Line: `a\ b \'c`, Want: []string{"a b", "'c"}},
+		{Name: "Joined", Line: `-msg="hello world"x`, Want: []string{"-msg=hello worldx"}},
+		{Name: "Empty Quoted", Line: `a '' ""`, Want: []string{"a", "", ""}},
+		{Name: "Line Continuation", Line: "a \\\nb", Want: []string{"a", "b"}},
+		{Name: "UTF-8", Line: `héllo "wörld"`, Want: []string{"héllo", "wörld"}},
+		{Name: "Unterminated Single", Line: `a 'b`, WantErr: true},
+		{Name: "Unterminated Double", Line: `a "b\"`, WantErr: true},
+		{Name: "Trailing Backslash", Line: `a \`, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := Tokenize(tt.Line)
+			if (err != nil) != tt.WantErr {
+				t.Fatalf("Tokenize() error = %v, wantErr %v", err, tt.WantErr)
+			}
+			if !reflect.DeepEqual(got, tt.Want) {
+				t.Errorf("Tokenize() = %q, want %q", got, tt.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package scli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Walk calls fn for c and each of its descendants in depth first order, passing the commands from c down to the
+// command being visited. If fn returns an error the walk stops and that error is returned.
+// Commands that are their own ancestor are skipped rather than visited again.
+func (c *Command) Walk(fn func(path []*Command) error) error {
+	return c.walk(nil, fn)
+}
+
+func (c *Command) walk(ancestors []*Command, fn func(path []*Command) error) error {
+	for _, ancestor := range ancestors {
+		if ancestor == c {
+			return nil
+		}
+	}
+
+	path := append(ancestors[:len(ancestors):len(ancestors)], c)
+	if err := fn(path); err != nil {
+		return err
+	}
+
+	for _, sub := range c.Subcommands {
+		if err := sub.walk(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tree renders c and its descendants as an indented tree, with any aliases in parentheses after each name followed
+// by its ShortHelp.
+func (c *Command) Tree() string {
+	var b strings.Builder
+
+	_ = c.Walk(func(path []*Command) error {
+		for i := 1; i < len(path); i++ {
+			last := isLastSubcommand(path[i-1], path[i])
+			switch {
+			case i < len(path)-1 && last:
+				b.WriteString("    ")
+			case i < len(path)-1:
+				b.WriteString("│   ")
+			case last:
+				b.WriteString("└── ")
+			default:
+				b.WriteString("├── ")
+			}
+		}
+
+		cmd := path[len(path)-1]
+		b.WriteString(cmd.Name())
+		if len(cmd.Aliases) > 0 {
+			b.WriteString(" (" + strings.Join(cmd.Aliases, ", ") + ")")
+		}
+		if cmd.ShortHelp != "" {
+			b.WriteString("  " + cmd.ShortHelp)
+		}
+		b.WriteByte('\n')
+		return nil
+	})
+
+	return b.String()
+}
+
+// GenDOT writes the command tree rooted at c to w as a Graphviz DOT graph, with a node for each command labeled with
+// its name, any aliases in parentheses and its ShortHelp, and an edge from each command to its subcommands.
+//
+//goland:noinspection GoUnhandledErrorResult
+func (c *Command) GenDOT(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(c.Name()))
+	fmt.Fprintln(&b, "  node [shape=box];")
+	_ = c.Walk(func(path []*Command) error {
+		cmd := path[len(path)-1]
+		label := cmd.Name()
+		if len(cmd.Aliases) > 0 {
+			label += " (" + strings.Join(cmd.Aliases, ", ") + ")"
+		}
+		if cmd.ShortHelp != "" {
+			label += "\n" + cmd.ShortHelp
+		}
+
+		fmt.Fprintf(&b, "  %s [label=%s];\n", dotQuote(commandPath(path)), dotQuote(label))
+		if len(path) > 1 {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(commandPath(path[:len(path)-1])), dotQuote(commandPath(path)))
+		}
+		return nil
+	})
+	fmt.Fprintln(&b, "}")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes s as a DOT string, where a newline becomes a line break in a label.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// isLastSubcommand reports whether sub is the last of the Subcommands of parent.
+func isLastSubcommand(parent, sub *Command) bool {
+	return len(parent.Subcommands) > 0 && parent.Subcommands[len(parent.Subcommands)-1] == sub
+}
@@ -0,0 +1,166 @@
+package scli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks the command tree rooted at c for misconfiguration, returning the first problem found.
+// It reports commands that are their own ancestor, subcommands of the same parent that share a name or alias,
+// subcommands that define any of the RootOnlyFlags of c, an ArgSpec with a variadic arg before its last, and commands
+// that can never run as they have no Exec, ExecChain, OnNoExec or Subcommands, wrapping a NoExecError.
+// Intended to be called from a unit test, so mistakes are caught before the tree is parsed.
+func (c *Command) Validate() error {
+	return c.validate(nil)
+}
+
+// validate implements Validate, where ancestors are the commands from the root down to the parent of c.
+func (c *Command) validate(ancestors []*Command) error {
+	path := append(ancestors[:len(ancestors):len(ancestors)], c)
+
+	for _, ancestor := range ancestors {
+		if ancestor == c {
+			return fmt.Errorf("%w: %s", ErrCommandCycle, commandPath(path))
+		}
+	}
+
+	if err := checkDuplicates(c.Subcommands, c.SubcommandNamePrefix); err != nil {
+		return fmt.Errorf("%s: %w", commandPath(path), err)
+	}
+
+	if err := c.checkRootOnlyFlags(path); err != nil {
+		return err
+	}
+
+	for i, arg := range c.ArgSpec {
+		if arg.Variadic && i < len(c.ArgSpec)-1 {
+			return fmt.Errorf("%s: only the last arg of ArgSpec can be variadic, received %s", commandPath(path), arg)
+		}
+	}
+
+	if c.exec() == nil && c.OnNoExec == nil && len(c.Subcommands) == 0 && !c.EnableVersionCommand {
+		return fmt.Errorf("%s: %w", commandPath(path), NoExecError{Command: c})
+	}
+
+	for _, sub := range c.Subcommands {
+		if err := sub.validate(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTree checks every parent in the command tree rooted at c for subcommands that share a name or alias, such
+// as ones grafted from different plugins, after removing any SubcommandNamePrefix of the parent. Unlike Validate it
+// reports all conflicts rather than the first, each with the path of the parent, wrapped by ErrDuplicateCommand. Intended to be called from a unit test once all subtrees have been added.
+func (c *Command) ValidateTree() error {
+	var conflicts []string
+
+	_ = c.Walk(func(path []*Command) error {
+		parent := path[len(path)-1]
+		for _, conflict := range nameConflicts(parent.Subcommands, parent.SubcommandNamePrefix) {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s", commandPath(path), conflict))
+		}
+		return nil
+	})
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateCommand, strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// ValidateDefaults checks the default value of every flag in the command tree rooted at c that has an entry in
+// FlagValidators, returning the first failure along with the command path and flag name.
+// Intended to be called from a unit test, so bad defaults are caught early.
+func (c *Command) ValidateDefaults() error {
+	return c.Walk(func(path []*Command) error {
+		cmd := path[len(path)-1]
+
+		names := make([]string, 0, len(cmd.FlagValidators))
+		for name := range cmd.FlagValidators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			var f *flag.Flag
+			if cmd.FlagSet != nil {
+				f = cmd.FlagSet.Lookup(name)
+			}
+			if f == nil {
+				return fmt.Errorf("%s: validator for undefined flag -%s", commandPath(path), name)
+			}
+
+			if err := cmd.FlagValidators[name](f.DefValue); err != nil {
+				return fmt.Errorf("%s: invalid default %q for flag -%s: %w", commandPath(path), f.DefValue, name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ValidateExamples checks that the Examples of every command in the tree rooted at c would parse, returning the first
+// that begins with the wrong name or uses an unknown subcommand or flag. Examples are split into args with Tokenize and
+// walked through the tree without parsing or executing anything, so flag values and args are not validated.
+// Intended to be called from a unit test, so documented examples do not drift from the commands.
+func (c *Command) ValidateExamples() error {
+	return c.Walk(func(path []*Command) error {
+		for _, example := range path[len(path)-1].Examples {
+			args, err := Tokenize(example)
+			if err == nil {
+				err = c.checkExample(args)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: example %q: %w", commandPath(path), example, err)
+			}
+		}
+		return nil
+	})
+}
+
+// checkExample walks the tokens of an example invocation through the tree rooted at c, as Parse would.
+func (c *Command) checkExample(args []string) error {
+	if len(args) == 0 || !strings.EqualFold(args[0], c.Name()) {
+		return fmt.Errorf("must begin with %q", c.Name())
+	}
+
+	cmd, args := c, args[1:]
+	for {
+		if cmd.ArgsTransform != nil {
+			args = cmd.ArgsTransform(append([]string{}, args...))
+		}
+
+		rest, err := cmd.checkFlags(args)
+		if err != nil {
+			return err
+		}
+
+		if len(rest) > 0 {
+			if sub := cmd.subcommand(rest[0]); sub != nil {
+				cmd, args = sub, rest[1:]
+				continue
+			}
+			if cmd.exec() == nil && len(cmd.Subcommands) > 0 {
+				return fmt.Errorf("%w: %q for %q", ErrUnknownCommand, rest[0], cmd.Name())
+			}
+		}
+		return nil
+	}
+}
+
+// checkRootOnlyFlags returns an error if c is not the root of path and defines any of the RootOnlyFlags of the root.
+func (c *Command) checkRootOnlyFlags(path []*Command) error {
+	if len(path) < 2 || c.FlagSet == nil {
+		return nil
+	}
+
+	for _, name := range path[0].RootOnlyFlags {
+		if c.FlagSet.Lookup(name) != nil {
+			return fmt.Errorf("%s: flag -%s may only be defined on the root command", commandPath(path), name)
+		}
+	}
+	return nil
+}
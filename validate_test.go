@@ -0,0 +1,202 @@
+package scli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommand_Validate(t *testing.T) {
+	rootOnly := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = rootOnly.String("config", "", "config file")
+
+	cycle := &Command{Usage: "cycle"}
+	cycle.Subcommands = []*Command{{Usage: "child", Subcommands: []*Command{cycle}}}
+
+	tests := []struct {
+		Name     string
+		Command  *Command
+		ErrCheck func(error) bool
+	}{
+		{
+			Name: "Valid",
+			Command: &Command{
+				Usage:       "root",
+				Subcommands: []*Command{{Usage: "foo", Exec: returnsNil}, {Usage: "bar", Exec: returnsNil}},
+			},
+		},
+		{
+			Name: "Duplicate",
+			Command: &Command{
+				Usage:       "root",
+				Subcommands: []*Command{{Usage: "foo", Exec: returnsNil}, {Usage: "bar", Aliases: []string{"foo"}, Exec: returnsNil}},
+			},
+			ErrCheck: errorIs(ErrDuplicateCommand),
+		},
+		{
+			Name: "Root Only Flag",
+			Command: &Command{
+				Usage:         "root",
+				RootOnlyFlags: []string{"config"},
+				Subcommands:   []*Command{{Usage: "sub", FlagSet: rootOnly, Exec: returnsNil}},
+			},
+			ErrCheck: func(err error) bool { return err != nil },
+		},
+		{
+			Name: "No Exec",
+			Command: &Command{
+				Usage:       "root",
+				Subcommands: []*Command{{Usage: "foo", Exec: returnsNil}, {Usage: "bar"}},
+			},
+			ErrCheck: errorAs[NoExecError](),
+		},
+		{
+			Name:     "Cycle",
+			Command:  &Command{Usage: "root", Subcommands: []*Command{cycle}},
+			ErrCheck: errorIs(ErrCommandCycle),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			err := tt.Command.Validate()
+			if checkError(err, tt.ErrCheck) || err == nil && tt.ErrCheck != nil {
+				t.Errorf("Validate() error %v", err)
+			}
+		})
+	}
+}
+
+func TestCommand_MaxDepth(t *testing.T) {
+	cmd := Command{
+		Usage:    "root",
+		FlagSet:  flag.NewFlagSet("root", flag.ContinueOnError),
+		MaxDepth: 1,
+		Subcommands: []*Command{{
+			Usage:   "a",
+			FlagSet: flag.NewFlagSet("a", flag.ContinueOnError),
+			Subcommands: []*Command{
+				{Usage: "b", FlagSet: flag.NewFlagSet("b", flag.ContinueOnError), Exec: returnsNil},
+			},
+		}},
+	}
+
+	if err := cmd.Parse([]string{"a", "b"}); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrMaxDepth)
+	}
+}
+
+func TestCommand_ParseCycle(t *testing.T) {
+	a := &Command{Usage: "a", Exec: returnsNil}
+	b := &Command{Usage: "b", Exec: returnsNil, Subcommands: []*Command{a}}
+	a.Subcommands = []*Command{b}
+
+	if err := a.Parse([]string{"b", "a", "b", "a"}); !errors.Is(err, ErrCommandCycle) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrCommandCycle)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = a.Run(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return for a cycle of selections")
+	}
+}
+
+func TestCommand_ValidateDefaults(t *testing.T) {
+	positive := func(value string) error {
+		if strings.HasPrefix(value, "-") {
+			return errors.New("must be positive")
+		}
+		return nil
+	}
+
+	fs := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = fs.Int("count", -1, "count")
+
+	cmd := Command{
+		Usage: "root",
+		Subcommands: []*Command{{
+			Usage:          "sub",
+			FlagSet:        fs,
+			FlagValidators: map[string]func(string) error{"count": positive},
+		}},
+	}
+
+	if err := cmd.ValidateDefaults(); err == nil || !strings.HasPrefix(err.Error(), "root sub: ") {
+		t.Errorf("ValidateDefaults() error = %v, want error for root sub", err)
+	}
+
+	_ = fs.Set("count", "1")
+	fs.Lookup("count").DefValue = "1"
+	if err := cmd.ValidateDefaults(); err != nil {
+		t.Errorf("ValidateDefaults() error = %v, want nil", err)
+	}
+}
+
+func TestCommand_ValidateExamples(t *testing.T) {
+	subFlags := flag.NewFlagSet("sub", flag.ContinueOnError)
+	_ = subFlags.String("name", "", "name")
+	_ = subFlags.Bool("force", false, "force")
+
+	tests := []struct {
+		Name     string
+		Examples []string
+		WantErr  bool
+	}{
+		{Name: "Valid", Examples: []string{"root sub -name foo -force arg", "root sub -- -arg"}},
+		{Name: "Wrong Root", Examples: []string{"other sub"}, WantErr: true},
+		{Name: "Unknown Flag", Examples: []string{"root sub -nope"}, WantErr: true},
+		{Name: "Missing Value", Examples: []string{"root sub -name"}, WantErr: true},
+		{Name: "Unknown Subcommand", Examples: []string{"root nope"}, WantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cmd := Command{
+				Usage:       "root",
+				Subcommands: []*Command{{Usage: "sub", FlagSet: subFlags, Examples: tt.Examples, Exec: returnsNil}},
+			}
+
+			if err := cmd.ValidateExamples(); (err != nil) != tt.WantErr {
+				t.Errorf("ValidateExamples() error = %v, wantErr %v", err, tt.WantErr)
+			}
+		})
+	}
+}
+
+func TestCommand_ValidateTree(t *testing.T) {
+	pluginA := &Command{Usage: "db", Subcommands: []*Command{{Usage: "migrate", Aliases: []string{"m"}, Exec: returnsNil}}}
+	pluginB := &Command{Usage: "cache", Subcommands: []*Command{{Usage: "flush", Exec: returnsNil}}}
+
+	cmd := &Command{Usage: "root", Subcommands: []*Command{pluginA, pluginB}}
+	if err := cmd.ValidateTree(); err != nil {
+		t.Fatalf("ValidateTree() error %v", err)
+	}
+
+	pluginA.Subcommands = append(pluginA.Subcommands, &Command{Usage: "mark", Aliases: []string{"M"}, Exec: returnsNil})
+	cmd.SubcommandNamePrefix = "root-"
+	cmd.Subcommands = append(cmd.Subcommands, &Command{Usage: "root-cache", Exec: returnsNil})
+
+	err := cmd.ValidateTree()
+	if !errors.Is(err, ErrDuplicateCommand) {
+		t.Fatalf("ValidateTree() error = %v, want %v", err, ErrDuplicateCommand)
+	}
+
+	for _, want := range []string{
+		`root: "cache" is used by both cache and root-cache`,
+		`root db: "M" is used by both migrate and mark`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateTree() error = %v, want it to contain %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package scli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+	"text/tabwriter"
+)
+
+// BuildInfo describes the build of a program, and is printed by the version subcommand added by
+// EnableVersionCommand. Fields left empty are printed as unknown.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// addVersionCommand adds the version subcommand to c if EnableVersionCommand is set and it has none.
+func (c *Command) addVersionCommand() {
+	if c.EnableVersionCommand && c.subcommand("version") == nil {
+		c.Subcommands = append(c.Subcommands, c.versionCommand())
+	}
+}
+
+// versionCommand returns the subcommand added to c by EnableVersionCommand.
+//
+//goland:noinspection GoUnhandledErrorResult
+func (c *Command) versionCommand() *Command {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	short := fs.Bool("short", false, "prints only the version")
+
+	return &Command{
+		Usage:         "version [flags]",
+		ShortHelp:     "prints version and build information",
+		FlagSet:       fs,
+		ArgsValidator: NoArgs(),
+		Exec: func(ctx context.Context, args []string) error {
+			w := OutputFromContext(ctx)
+			if *short {
+				fmt.Fprintln(w, orUnknown(c.BuildInfo.Version))
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+			fmt.Fprintf(tw, "Version:\t%s\n", orUnknown(c.BuildInfo.Version))
+			fmt.Fprintf(tw, "Commit:\t%s\n", orUnknown(c.BuildInfo.Commit))
+			fmt.Fprintf(tw, "Date:\t%s\n", orUnknown(c.BuildInfo.Date))
+			fmt.Fprintf(tw, "Go:\t%s\n", runtime.Version())
+			fmt.Fprintf(tw, "OS/Arch:\t%s/%s\n", runtime.GOOS, runtime.GOARCH)
+			return tw.Flush()
+		},
+	}
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}